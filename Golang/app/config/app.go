@@ -2,9 +2,14 @@ package config
 
 import "boilerplate/app/utils"
 
+var APP_NAME = utils.LoadEnvDefault("APP_NAME", "Go Fiber Boilerplate")
 var APP_HOST = utils.LoadEnv("APP_HOST")
 var APP_PORT = utils.LoadEnv("APP_PORT")
+var APP_URL = utils.LoadEnvDefault("APP_URL", "http://localhost:3000")
 
 var CORS_ALLOWED_ORIGINS = utils.LoadEnv("CORS_ALLOWED_ORIGINS")
+var CORS_ALLOW_CREDENTIALS = utils.LoadEnvBool("CORS_ALLOW_CREDENTIALS", false)
 
 var JWT_SECRET = utils.LoadEnv("JWT_SECRET")
+var JWT_KEYS = utils.LoadEnv("JWT_KEYS")
+var JWT_ACTIVE_KID = utils.LoadEnv("JWT_ACTIVE_KID")
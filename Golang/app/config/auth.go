@@ -0,0 +1,30 @@
+package config
+
+import (
+	"boilerplate/app/utils"
+	"time"
+)
+
+var ADMIN_EMAIL = utils.LoadEnv("ADMIN_EMAIL")
+
+var PASSWORD_RESET_TTL = utils.LoadEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+
+var EMAIL_VERIFICATION_TTL = utils.LoadEnvDuration("EMAIL_VERIFICATION_TTL", time.Hour*24)
+var EMAIL_VERIFICATION_REQUIRED = utils.LoadEnvBool("EMAIL_VERIFICATION_REQUIRED", true)
+
+var MAGIC_LINK_TTL = utils.LoadEnvDuration("MAGIC_LINK_TTL", time.Minute*15)
+
+var CONFIRMATION_TOKEN_TTL = utils.LoadEnvDuration("CONFIRMATION_TOKEN_TTL", time.Minute*15)
+
+var MFA_TOKEN_TTL = utils.LoadEnvDuration("MFA_TOKEN_TTL", time.Minute*5)
+
+var LOGIN_MAX_ATTEMPTS = utils.LoadEnvInt("LOGIN_MAX_ATTEMPTS", 5)
+var LOGIN_IP_MAX_ATTEMPTS = utils.LoadEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20)
+var LOGIN_ATTEMPT_WINDOW = utils.LoadEnvDuration("LOGIN_ATTEMPT_WINDOW", time.Hour)
+var LOGIN_LOCKOUT_BASE = utils.LoadEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute)
+var LOGIN_LOCKOUT_MAX = utils.LoadEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour)
+
+var IMPERSONATION_TTL = utils.LoadEnvDuration("IMPERSONATION_TTL", time.Minute*15)
+
+var ACCOUNT_DELETION_GRACE = utils.LoadEnvDuration("ACCOUNT_DELETION_GRACE", time.Hour*24*30)
+var ACCOUNT_PURGE_INTERVAL = utils.LoadEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour)
@@ -0,0 +1,7 @@
+package config
+
+import "boilerplate/app/utils"
+
+var COOKIE_SECURE = utils.LoadEnvBool("COOKIE_SECURE", true)
+var COOKIE_SAMESITE = utils.LoadEnvDefault("COOKIE_SAMESITE", "Lax")
+var COOKIE_DOMAIN = utils.LoadEnv("COOKIE_DOMAIN")
@@ -0,0 +1,11 @@
+package config
+
+import "boilerplate/app/utils"
+
+var PASSWORD_HASH_ALGORITHM = utils.LoadEnvDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+
+var BCRYPT_COST = utils.LoadEnvInt("BCRYPT_COST", 10)
+
+var ARGON2_MEMORY = utils.LoadEnvInt("ARGON2_MEMORY", 64*1024)
+var ARGON2_ITERATIONS = utils.LoadEnvInt("ARGON2_ITERATIONS", 3)
+var ARGON2_PARALLELISM = utils.LoadEnvInt("ARGON2_PARALLELISM", 2)
@@ -0,0 +1,7 @@
+package config
+
+import "boilerplate/app/utils"
+
+var MAIL_DRIVER = utils.LoadEnvDefault("MAIL_DRIVER", "log")
+var MAIL_FROM = utils.LoadEnvDefault("MAIL_FROM", "no-reply@localhost")
+var MAIL_FILE_PATH = utils.LoadEnvDefault("MAIL_FILE_PATH", "storage/mail.log")
@@ -0,0 +1,6 @@
+package config
+
+import "boilerplate/app/utils"
+
+// OAUTH_CLIENTS lists the services allowed to introspect and revoke tokens as "id:secret,id:secret"
+var OAUTH_CLIENTS = utils.LoadEnv("OAUTH_CLIENTS")
@@ -0,0 +1,12 @@
+package config
+
+import "boilerplate/app/utils"
+
+var OIDC_PROVIDER = utils.LoadEnvDefault("OIDC_PROVIDER", "oidc")
+var OIDC_CLIENT_ID = utils.LoadEnv("OIDC_CLIENT_ID")
+var OIDC_CLIENT_SECRET = utils.LoadEnv("OIDC_CLIENT_SECRET")
+var OIDC_AUTH_URL = utils.LoadEnv("OIDC_AUTH_URL")
+var OIDC_TOKEN_URL = utils.LoadEnv("OIDC_TOKEN_URL")
+var OIDC_USERINFO_URL = utils.LoadEnv("OIDC_USERINFO_URL")
+var OIDC_REDIRECT_URL = utils.LoadEnv("OIDC_REDIRECT_URL")
+var OIDC_SCOPES = utils.LoadEnvDefault("OIDC_SCOPES", "openid email profile")
@@ -0,0 +1,10 @@
+package config
+
+import "boilerplate/app/utils"
+
+var PASSWORD_MIN_LENGTH = utils.LoadEnvInt("PASSWORD_MIN_LENGTH", 8)
+var PASSWORD_MAX_LENGTH = utils.LoadEnvInt("PASSWORD_MAX_LENGTH", 64)
+var PASSWORD_MIN_CLASSES = utils.LoadEnvInt("PASSWORD_MIN_CLASSES", 2)
+var PASSWORD_MIN_SCORE = utils.LoadEnvInt("PASSWORD_MIN_SCORE", 2)
+
+var BREACHED_PASSWORDS_PATH = utils.LoadEnv("BREACHED_PASSWORDS_PATH")
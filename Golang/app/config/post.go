@@ -0,0 +1,13 @@
+package config
+
+import (
+	"boilerplate/app/utils"
+	"time"
+)
+
+var POST_SCHEDULER_INTERVAL = utils.LoadEnvDuration("POST_SCHEDULER_INTERVAL", time.Minute)
+
+var COMMENT_MAX_DEPTH = utils.LoadEnvInt("COMMENT_MAX_DEPTH", 5)
+
+// REACTION_TYPES lists the reactions users can leave on posts as "like,love"
+var REACTION_TYPES = utils.LoadEnvDefault("REACTION_TYPES", "like,love,laugh,wow,sad")
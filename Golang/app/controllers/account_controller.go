@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"archive/zip"
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/hasher"
+	"boilerplate/app/mailer"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// RequestConfirmation godoc
+// @Summary      Request confirmation token
+// @Description  Email a token that confirms sensitive changes in place of the password, for accounts that sign in without one
+// @Tags         Account
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/confirmation [post]
+func RequestConfirmation(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	if user.PasswordHash != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Confirm changes with your password",
+			Data:    nil,
+		})
+	}
+
+	if err := sendConfirmationEmail(*user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not send confirmation email",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "A confirmation token has been sent to your email",
+		Data:    nil,
+	})
+}
+
+// DestroyAccount godoc
+// @Summary      Delete account
+// @Description  Delete the current user account, confirmed with the password or a confirmation token for accounts without one. Posts are hidden at once and everything is permanently purged once the grace period is over
+// @Tags         Account
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.DeleteAccountRequest true "Delete account request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me [delete]
+func DestroyAccount(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	deleteRequest := new(models.DeleteAccountRequest)
+	if err := c.BodyParser(deleteRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(deleteRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check password
+	if !confirmUser(*user, deleteRequest.Password, deleteRequest.ConfirmationToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid password or confirmation token",
+			Data:    nil,
+		})
+	}
+
+	// Soft delete the user and revoke its API keys, the purge job removes the rest later
+	err = pg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ApiKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(user).Error
+	})
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not delete account",
+			Data:    nil,
+		})
+	}
+
+	// Log out every session
+	if err := middlewares.RevokeAllSessions(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not delete account",
+			Data:    nil,
+		})
+	}
+
+	// clear cookie
+	clearRefreshCookie(c)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Account deleted successfully",
+		Data: map[string]interface{}{
+			"purge_at": time.Now().Add(config.ACCOUNT_DELETION_GRACE),
+		},
+	})
+}
+
+// ExportAccount godoc
+// @Summary      Export account data
+// @Description  Download a ZIP archive with the profile, posts and comments of the current user as JSON
+// @Tags         Account
+// @Produce      application/zip
+// @Security	 ApiKeyAuth
+// @Success      200  {file}    file
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/export [get]
+func ExportAccount(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get all posts of the user
+	var posts []models.Post
+	if err := pg.DB.Where("user_id = ?", user.ID).Order("id asc").Find(&posts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not export account",
+			Data:    nil,
+		})
+	}
+
+	// Get all comments of the user
+	var comments []models.Comment
+	if err := pg.DB.Where("user_id = ?", user.ID).Order("id asc").Find(&comments).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not export account",
+			Data:    nil,
+		})
+	}
+
+	// Build archive
+	archive, err := exportArchive(map[string]interface{}{
+		"profile.json":  user.Profile(),
+		"posts.json":    posts,
+		"comments.json": comments,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not export account",
+			Data:    nil,
+		})
+	}
+
+	c.Attachment("account-export.zip")
+	c.Set(fiber.HeaderContentType, "application/zip")
+
+	return c.Status(fiber.StatusOK).Send(archive)
+}
+
+// exportArchive zips every value as an indented JSON file
+func exportArchive(files map[string]interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	for name, value := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// confirmUser checks a sensitive change with the password of the user, or with a token
+// from RequestConfirmation when the account has no password (OpenID Connect only)
+func confirmUser(user models.User, password string, confirmationToken string) bool {
+	if user.PasswordHash != "" {
+		return hasher.Verify(user.PasswordHash, password)
+	}
+
+	if confirmationToken == "" {
+		return false
+	}
+
+	owner, err := middlewares.ConsumeOneTimeToken(confirmationToken, models.TokenPurposeConfirmation)
+
+	return err == nil && owner.ID == user.ID
+}
+
+func sendConfirmationEmail(user models.User) error {
+	token, err := middlewares.IssueOneTimeToken(user.ID, models.TokenPurposeConfirmation, config.CONFIRMATION_TOKEN_TTL)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(user.Email, "Confirm your change", fmt.Sprintf(
+		"Hi %s,\n\nUse the token below to confirm the change to your account. It can be used once and expires in %s.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+		user.Name, config.CONFIRMATION_TOKEN_TTL, token,
+	))
+}
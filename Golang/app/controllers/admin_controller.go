@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Impersonate godoc
+// @Summary      Impersonate user
+// @Description  Get a short-lived access token acting as the user, for support. Account changes are refused with it and every impersonation is audited.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "User ID"
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  models.ImpersonateResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/users/{id}/impersonate [post]
+func Impersonate(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	admin, err := middlewares.FindUserByToken(c)
+	if err != nil || admin == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get target user
+	var user models.User
+	if err := db.DB.Preload("Roles.Permissions").First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "User not found",
+			Data:    nil,
+		})
+	}
+
+	if user.ID == admin.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "You can't impersonate yourself",
+			Data:    nil,
+		})
+	}
+
+	// Impersonation never grants admin rights
+	if user.HasPermission(models.PermissionUsersManage) || user.HasPermission(models.PermissionUsersImpersonate) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+			Status:  false,
+			Message: "Administrators can't be impersonated",
+			Data:    nil,
+		})
+	}
+
+	// Record before handing out the token
+	if err := middlewares.RecordAudit(c, models.AuditImpersonationStarted, admin.ID, &user.ID, map[string]interface{}{
+		"ttl": config.IMPERSONATION_TTL.String(),
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not impersonate user",
+			Data:    nil,
+		})
+	}
+
+	// Generate impersonation token
+	token, err := middlewares.GenerateImpersonationJWT(user, *admin, config.IMPERSONATION_TTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not impersonate user",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Impersonating " + user.Email,
+		Data: models.ImpersonateResponse{
+			Token:     token,
+			ExpiresAt: time.Now().Add(config.IMPERSONATION_TTL),
+		},
+	})
+}
+
+// IndexAuditLog godoc
+// @Summary      Get audit logs
+// @Description  Get the audit trail, newest first, optionally filtered by actor, subject or action
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param	 page query int true "Page number" default(1)
+// @Param	 perPage query int true "Number of entries per page" default(10)
+// @Param	 actor_id query int false "Actor user ID"
+// @Param	 subject_id query int false "Subject user ID"
+// @Param	 action query string false "Action"
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.AuditLog
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Router       /admin/audit-logs [get]
+func IndexAuditLog(c *fiber.Ctx) error {
+	// get query params page and perPage
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("perPage", "10"))
+
+	query := db.DB.Model(&models.AuditLog{})
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if subjectID := c.Query("subject_id"); subjectID != "" {
+		query = query.Where("subject_id = ?", subjectID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var logs []models.AuditLog
+	query.Scopes(db.Paginate(page, perPage)).Order("id desc").Find(&logs)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched audit logs",
+		Data:    logs,
+	})
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexApiKey godoc
+// @Summary      Get API keys
+// @Description  Get the personal API keys of the current user, the secret part is never returned
+// @Tags         API Key
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.ApiKey
+// @Failure      401  {object}  utils.Response
+// @Router       /me/api-keys [get]
+func IndexApiKey(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	var keys []models.ApiKey
+	db.DB.Where("user_id = ?", user.ID).Order("id desc").Find(&keys)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched API keys",
+		Data:    keys,
+	})
+}
+
+// StoreApiKey godoc
+// @Summary      Create API key
+// @Description  Create a personal API key limited to the given scopes, the key is only shown once
+// @Tags         API Key
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.CreateApiKeyRequest true "Create API key request"
+// @Success      200  {object}  models.CreateApiKeyResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/api-keys [post]
+func StoreApiKey(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	// get and parse request body
+	keyRequest := new(models.CreateApiKeyRequest)
+	if err := c.BodyParser(keyRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(keyRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// a key can't be granted more than the user is allowed to do
+	for _, scope := range keyRequest.Scopes {
+		if !user.HasPermission(scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+				Status:  false,
+				Message: "Invalid scope " + scope,
+				Data:    []interface{}{},
+			})
+		}
+	}
+
+	if keyRequest.ExpiresAt != nil && keyRequest.ExpiresAt.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Expiry must be in the future",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Generate key
+	raw, prefix, err := middlewares.GenerateApiKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to create API key",
+			Data:    []interface{}{},
+		})
+	}
+
+	key := models.ApiKey{
+		UserID:    user.ID,
+		Name:      keyRequest.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(raw),
+		Scopes:    keyRequest.Scopes,
+		ExpiresAt: keyRequest.ExpiresAt,
+	}
+
+	if err := db.DB.Create(&key).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to create API key",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully created API key, copy it now as it won't be shown again",
+		Data: models.CreateApiKeyResponse{
+			ApiKey: key,
+			Key:    raw,
+		},
+	})
+}
+
+// DestroyApiKey godoc
+// @Summary      Revoke API key
+// @Description  Revoke a personal API key of the current user
+// @Tags         API Key
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "API key ID"
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/api-keys/{id} [delete]
+func DestroyApiKey(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	// find key by id, scoped to the current user
+	var key models.ApiKey
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Params("id"), user.ID).First(&key).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "API key not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	// revoke key
+	if key.RevokedAt == nil {
+		if err := db.DB.Model(&key).Update("revoked_at", time.Now()).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+				Status:  false,
+				Message: "Failed to revoke API key",
+				Data: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully revoked API key",
+		Data:    map[string]interface{}{},
+	})
+}
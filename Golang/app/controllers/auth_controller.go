@@ -3,20 +3,35 @@ package controllers
 import (
 	"boilerplate/app/config"
 	pg "boilerplate/app/db"
+	"boilerplate/app/hasher"
+	"boilerplate/app/mailer"
 	"boilerplate/app/middlewares"
 	"boilerplate/app/models"
+	"boilerplate/app/policies"
 	"boilerplate/app/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	unlockLoginPurpose = "unlock_login"
+	refreshTokenCookie = "refreshToken"
+)
+
+// dummyPasswordHash is compared against for unknown emails so they take as long as a wrong password
+var dummyPasswordHash, _ = hasher.Hash("not-a-real-password")
+
 // Login godoc
 // @Summary      Perform login
-// @Description  Login with email and password
+// @Description  Login with email and password, returns an MFA token instead when two-factor authentication is enabled
 // @Tags         Auth
 // @Accept       json
 // @Headers      Content-Type application/json
@@ -24,7 +39,7 @@ import (
 // @Param        request body models.LoginRequest true "Login request"
 // @Success      200  {array}   models.LoginResponse
 // @Failure      400  {object}  utils.Response
-// @Failure      404  {object}  utils.Response
+// @Failure      429  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /login [post]
 func Login(c *fiber.Ctx) error {
@@ -38,6 +53,8 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	loginRequest.Email = utils.NormalizeEmail(loginRequest.Email)
+
 	// Validate user input
 	validationErrors := utils.GlobalValidator.Validate(loginRequest)
 	if len(validationErrors) > 0 {
@@ -48,18 +65,32 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if user exists
+	// Refuse while the email or IP address is locked out
+	if wait := middlewares.LoginRetryAfter(loginRequest.Email, c.IP()); wait > 0 {
+		return tooManyLoginAttempts(c, wait)
+	}
+
+	// Check if user exists, an unknown email is answered like a wrong password
 	var user models.User
-	if err := pg.DB.Where("email = ?", loginRequest.Email).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+	if err := pg.DB.Where("lower(email) = ?", loginRequest.Email).First(&user).Error; err != nil {
+		_ = hasher.Verify(dummyPasswordHash, loginRequest.PasswordHash)
+		middlewares.RecordLoginFailure(loginRequest.Email, c.IP(), nil, "unknown_email")
+
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
 			Status:  false,
-			Message: "User not found",
+			Message: "Invalid email or password",
 			Data:    nil,
 		})
 	}
 
 	// Check if password is correct
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginRequest.PasswordHash)); err != nil {
+	if !hasher.Verify(user.PasswordHash, loginRequest.PasswordHash) {
+		if locked := middlewares.RecordLoginFailure(loginRequest.Email, c.IP(), &user.ID, "invalid_password"); locked {
+			if err := sendUnlockEmail(user); err != nil {
+				log.Printf("unlock email for user %d: %v", user.ID, err)
+			}
+		}
+
 		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
 			Status:  false,
 			Message: "Invalid email or password",
@@ -67,33 +98,72 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate JWT
-	token, err := middlewares.GenerateJWT(user)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+	// Upgrade the stored hash to the current algorithm and parameters
+	if hasher.NeedsRehash(user.PasswordHash) {
+		if hashedPassword, err := hasher.Hash(loginRequest.PasswordHash); err == nil {
+			if err := pg.DB.Model(&user).Update("password_hash", hashedPassword).Error; err != nil {
+				log.Printf("rehash password for user %d: %v", user.ID, err)
+			}
+		}
+	}
+
+	// With two-factor enabled the login only succeeds once the code is verified
+	if user.TwoFactorEnabledAt == nil {
+		middlewares.RecordLoginSuccess(loginRequest.Email, c.IP(), user.ID)
+	}
+
+	return completeLogin(c, user)
+}
+
+// UnlockLogin godoc
+// @Summary      Unlock login
+// @Description  Lift a login lockout using the token from the unlock email
+// @Tags         Auth
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.UnlockLoginRequest true "Unlock login request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Router       /login/unlock [post]
+func UnlockLogin(c *fiber.Ctx) error {
+	// Get and parse user input
+	unlockRequest := new(models.UnlockLoginRequest)
+	if err := c.BodyParser(unlockRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
 			Status:  false,
-			Message: "Could not login user",
+			Message: "Invalid request payload",
 			Data:    nil,
 		})
 	}
 
-	// explode token with | as delimiter
-	tokenList := strings.Split(token, "|")
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(unlockRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
 
-	// set cookie
-	c.Cookie(&fiber.Cookie{
-		Name:    "refreshToken",
-		Value:   tokenList[1],
-		Expires: time.Now().Add(time.Hour * 24),
-	})
+	// Check token signature and expiry
+	claims, err := middlewares.ParsePurposeJWT(unlockRequest.Token, unlockLoginPurpose)
+	email, _ := claims["email"].(string)
+	if err != nil || email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired unlock link",
+			Data:    nil,
+		})
+	}
+
+	middlewares.UnlockLogin(email)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
-		Message: "User logged in successfully",
-		Data: models.LoginResponse{
-			Token:        tokenList[0],
-			RefreshToken: tokenList[1],
-		},
+		Message: "Login unlocked successfully",
+		Data:    nil,
 	})
 }
 
@@ -105,9 +175,10 @@ func Login(c *fiber.Ctx) error {
 // @Headers      Content-Type application/json
 // @Produce      json
 // @Param        request body models.RegisterRequest true "Register request"
-// @Success      200  {array}   models.User
+// @Success      200  {array}   models.Profile
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /register [post]
 func Register(c *fiber.Ctx) error {
@@ -121,6 +192,8 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	registerRequest.Email = utils.NormalizeEmail(registerRequest.Email)
+
 	// Validate user input
 	validationErrors := utils.GlobalValidator.Validate(registerRequest)
 	if len(validationErrors) > 0 {
@@ -131,8 +204,26 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Check password policy
+	if policyErrors := policies.CheckPassword(registerRequest.PasswordHash, registerRequest.Email, registerRequest.Name); len(policyErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    policyErrors,
+		})
+	}
+
+	// Check if email is already registered
+	if emailTaken(registerRequest.Email, 0) {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Email is already registered",
+			Data:    nil,
+		})
+	}
+
 	// Generate password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerRequest.PasswordHash), bcrypt.DefaultCost)
+	hashedPassword, err := hasher.Hash(registerRequest.PasswordHash)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
@@ -141,11 +232,22 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Get default role
+	var role models.Role
+	if err := pg.DB.Where("name = ?", models.RoleAuthor).First(&role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not register user",
+			Data:    nil,
+		})
+	}
+
 	// Create user
 	user := models.User{
 		Name:         registerRequest.Name,
 		Email:        registerRequest.Email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+		Roles:        []models.Role{role},
 	}
 
 	// Save user
@@ -157,34 +259,44 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Send verification link, the user can ask for a new one if this fails
+	if err := sendVerificationEmail(user, user.Email); err != nil {
+		log.Printf("verification email for user %d: %v", user.ID, err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
 		Message: "User registered successfully",
-		Data:    user,
+		Data:    user.Profile(),
 	})
 }
 
 // RefreshToken godoc
 // @Summary      Refresh a user access token
-// @Description  Refresh a user access token and return the new access token
+// @Description  Rotate the refresh token and return a new access token with the new refresh token, a revoked session can't be refreshed
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
+// @Param        request body models.RefreshRequest false "Refresh token, the cookie is used when omitted"
+// @Param        X-CSRF-Token header string false "Value of the csrfToken cookie, required when the refresh token comes from the cookie"
 // @Success      200  {object}  models.RefreshResponse
 // @Failure      400  {object}  utils.Response
-// @Failure      404  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
 // @Router       /refresh [post]
 func RefreshToken(c *fiber.Ctx) error {
-	// Get cookie
-	cookie := c.Cookies("refreshToken")
-
-	// Parse token
-	token, err := jwt.ParseWithClaims(cookie, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWT_SECRET), nil
-	})
+	// Get refresh token from the body or the cookie
+	refreshToken, fromCookie := refreshTokenFromRequest(c)
+	if fromCookie && refreshToken != "" && !middlewares.ValidCsrfToken(c) {
+		return invalidCsrfToken(c)
+	}
 
+	// Rotate refresh token, a reused token revokes the whole family
+	user, session, refreshToken, err := middlewares.RotateRefreshToken(refreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
+		clearRefreshCookie(c)
+
 		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
 			Status:  false,
 			Message: "Could not refresh token",
@@ -192,54 +304,113 @@ func RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get claims
-	claims := token.Claims.(jwt.MapClaims)
+	// Generate new access token
+	token, err := middlewares.GenerateAccessJWT(*user, session.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not refresh token",
+			Data:    nil,
+		})
+	}
 
-	// Check if token is expired
-	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+	// set cookie
+	if err := setRefreshCookie(c, refreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
 			Message: "Could not refresh token",
 			Data:    nil,
 		})
 	}
 
-	// Get user id
-	userId := int(claims["user_id"].(float64))
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Token refreshed successfully",
+		Data: models.RefreshResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+		},
+	})
+}
 
-	// Check if user exists
-	var user models.User
-	if err := pg.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the current refresh token and clear the refresh token cookie
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.RefreshRequest false "Refresh token, the cookie is used when omitted"
+// @Param        X-CSRF-Token header string false "Value of the csrfToken cookie, required when the refresh token comes from the cookie"
+// @Success      200  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /logout [post]
+func Logout(c *fiber.Ctx) error {
+	// Get refresh token from the body or the cookie
+	refreshToken, fromCookie := refreshTokenFromRequest(c)
+	if fromCookie && refreshToken != "" && !middlewares.ValidCsrfToken(c) {
+		return invalidCsrfToken(c)
+	}
+
+	// Revoke refresh token if any, an unknown token is already logged out
+	if refreshToken != "" {
+		if err := middlewares.RevokeRefreshToken(refreshToken); err != nil && !errors.Is(err, middlewares.ErrInvalidRefreshToken) {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+				Status:  false,
+				Message: "Could not logout user",
+				Data:    nil,
+			})
+		}
+	}
+
+	// clear cookie
+	clearRefreshCookie(c)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "User logged out successfully",
+		Data:    nil,
+	})
+}
+
+// LogoutAll godoc
+// @Summary      Logout everywhere
+// @Description  Revoke every refresh token and access token of the current user
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /logout-all [post]
+func LogoutAll(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
 			Status:  false,
-			Message: "Could not refresh token",
+			Message: "Unauthorized",
 			Data:    nil,
 		})
 	}
 
-	// Generate new token
-	refreshToken, err := middlewares.GenerateRefreshJWT(user)
-	if err != nil {
+	// Revoke all sessions
+	if err := middlewares.RevokeAllSessions(user.ID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
-			Message: "Could not refresh token",
+			Message: "Could not logout user",
 			Data:    nil,
 		})
 	}
 
-	// set cookie
-	c.Cookie(&fiber.Cookie{
-		Name:    "refreshToken",
-		Value:   refreshToken,
-		Expires: time.Now().Add(time.Hour * 24),
-	})
+	// clear cookie
+	clearRefreshCookie(c)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
-		Message: "Token refreshed successfully",
-		Data: models.RefreshResponse{
-			RefreshToken: refreshToken,
-		},
+		Message: "User logged out from all sessions successfully",
+		Data:    nil,
 	})
 }
 
@@ -251,7 +422,7 @@ func RefreshToken(c *fiber.Ctx) error {
 // @Headers      Content-Type application/json
 // @Produce      json
 // @Security	 ApiKeyAuth
-// @Success      200  {array}   models.User
+// @Success      200  {array}   models.Profile
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
@@ -265,9 +436,7 @@ func Profile(c *fiber.Ctx) error {
 	auth = []byte(splitToken[1])
 
 	// Parse token
-	token, err := jwt.ParseWithClaims(string(auth), jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWT_SECRET), nil
-	})
+	token, err := middlewares.ParseJWT(string(auth), jwt.MapClaims{})
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
@@ -290,7 +459,7 @@ func Profile(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
 		Message: "User profile retrieved successfully",
-		Data:    user,
+		Data:    user.Profile(),
 	})
 }
 
@@ -303,7 +472,7 @@ func Profile(c *fiber.Ctx) error {
 // @Produce      json
 // @Security	 ApiKeyAuth
 // @Param        request body models.UpdateUserRequest true "Update profile request"
-// @Success      200  {array}   models.User
+// @Success      200  {array}   models.Profile
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
@@ -317,9 +486,7 @@ func UpdateProfile(c *fiber.Ctx) error {
 	auth = []byte(splitToken[1])
 
 	// Parse token
-	token, err := jwt.ParseWithClaims(string(auth), jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWT_SECRET), nil
-	})
+	token, err := middlewares.ParseJWT(string(auth), jwt.MapClaims{})
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
@@ -372,6 +539,107 @@ func UpdateProfile(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
 		Message: "User profile updated successfully",
-		Data:    user,
+		Data:    user.Profile(),
+	})
+}
+
+func tooManyLoginAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+
+	return c.Status(fiber.StatusTooManyRequests).JSON(utils.Response{
+		Status:  false,
+		Message: "Too many failed login attempts, please try again later",
+		Data:    nil,
+	})
+}
+
+func sendUnlockEmail(user models.User) error {
+	token, err := middlewares.GeneratePurposeJWT(user.ID, unlockLoginPurpose, config.LOGIN_LOCKOUT_MAX, jwt.MapClaims{
+		"email": user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := config.APP_URL + "/unlock-login?token=" + url.QueryEscape(token)
+
+	return mailer.Send(user.Email, "Your account has been locked", fmt.Sprintf(
+		"Hi %s,\n\nWe locked logins to your account after several failed attempts. If it was you, open the link below to unlock it now.\n\n%s\n\nIf it wasn't you, consider resetting your password.",
+		user.Name, link,
+	))
+}
+
+// completeLogin finishes a login once the first factor is verified,
+// asking for the second factor before issuing tokens when it is enabled
+func completeLogin(c *fiber.Ctx, user models.User) error {
+	if user.TwoFactorEnabledAt != nil {
+		return mfaChallenge(c, user)
+	}
+
+	return issueLoginTokens(c, user)
+}
+
+// issueLoginTokens generates the access and refresh tokens of a successful login
+func issueLoginTokens(c *fiber.Ctx, user models.User) error {
+	// Generate JWT
+	token, err := middlewares.GenerateJWT(user, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login user",
+			Data:    nil,
+		})
+	}
+
+	// explode token with | as delimiter
+	tokenList := strings.Split(token, "|")
+
+	// set cookie
+	if err := setRefreshCookie(c, tokenList[1]); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login user",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "User logged in successfully",
+		Data: models.LoginResponse{
+			Token:        tokenList[0],
+			RefreshToken: tokenList[1],
+		},
+	})
+}
+
+// refreshTokenFromRequest reads the refresh token from the JSON body, as sent by mobile
+// and CLI clients, or else from the cookie, which browsers must back with a CSRF token
+func refreshTokenFromRequest(c *fiber.Ctx) (string, bool) {
+	refreshRequest := new(models.RefreshRequest)
+	if err := c.BodyParser(refreshRequest); err == nil && refreshRequest.RefreshToken != "" {
+		return refreshRequest.RefreshToken, false
+	}
+
+	return c.Cookies(refreshTokenCookie), true
+}
+
+func setRefreshCookie(c *fiber.Ctx, token string) error {
+	expires := time.Now().Add(time.Hour * 24 * 30)
+	c.Cookie(middlewares.SecureCookie(refreshTokenCookie, token, expires, true))
+
+	return middlewares.IssueCsrfToken(c, expires)
+}
+
+func clearRefreshCookie(c *fiber.Ctx) {
+	c.Cookie(middlewares.SecureCookie(refreshTokenCookie, "", time.Now().Add(-time.Hour), true))
+	middlewares.ClearCsrfToken(c)
+}
+
+func invalidCsrfToken(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+		Status:  false,
+		Message: "Invalid CSRF token",
+		Data:    nil,
 	})
 }
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/policies"
+	"boilerplate/app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexCategory godoc
+// @Summary      Get categories
+// @Description  Get the category tree, each category counts the published posts in it and its subcategories
+// @Tags         Category
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.Category
+// @Router       /categories [get]
+func IndexCategory(c *fiber.Ctx) error {
+	var categories []models.Category
+	db.DB.Order("name asc").Find(&categories)
+
+	// count published posts per category
+	var counts []struct {
+		CategoryID uint
+		Count      int64
+	}
+	db.DB.Model(&models.Post{}).
+		Scopes(policies.VisiblePosts(nil)).
+		Select("category_id, COUNT(*) AS count").
+		Where("category_id IS NOT NULL").
+		Group("category_id").
+		Scan(&counts)
+
+	postCounts := map[uint]int64{}
+	for _, count := range counts {
+		postCounts[count.CategoryID] = count.Count
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched categories",
+		Data:    categoryTree(categories, postCounts),
+	})
+}
+
+// StoreCategory godoc
+// @Summary      Create category
+// @Description  Create a category, optionally under a parent category
+// @Tags         Category
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Param        request body models.CreateCategoryRequest true "Create category request"
+// @Success      200  {object}  models.Category
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /categories [post]
+func StoreCategory(c *fiber.Ctx) error {
+	// get and parse request body
+	categoryRequest := new(models.CreateCategoryRequest)
+	if err := c.BodyParser(categoryRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(categoryRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	slug := utils.Slugify(categoryRequest.Name)
+	if slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Category name must contain letters or digits",
+			Data:    []interface{}{},
+		})
+	}
+
+	if categoryRequest.ParentID != nil && !categoryExists(*categoryRequest.ParentID) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Parent category not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	var count int64
+	db.DB.Model(&models.Category{}).Where("slug = ?", slug).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Category already exists",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Create new category
+	category := models.Category{
+		Name:     categoryRequest.Name,
+		Slug:     slug,
+		ParentID: categoryRequest.ParentID,
+	}
+
+	if err := db.DB.Create(&category).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to create category",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully created category",
+		Data:    category,
+	})
+}
+
+func categoryExists(id uint) bool {
+	var count int64
+	db.DB.Model(&models.Category{}).Where("id = ?", id).Count(&count)
+
+	return count > 0
+}
+
+// categoryTree nests the categories under their parents, adding the post counts of
+// the subcategories to their ancestors
+func categoryTree(categories []models.Category, postCounts map[uint]int64) []models.Category {
+	children := map[uint][]models.Category{}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+
+		children[*category.ParentID] = append(children[*category.ParentID], category)
+	}
+
+	var build func(category *models.Category)
+	build = func(category *models.Category) {
+		category.PostCount = postCounts[category.ID]
+		category.Children = children[category.ID]
+		for i := range category.Children {
+			build(&category.Children[i])
+			category.PostCount += category.Children[i].PostCount
+		}
+	}
+
+	for i := range roots {
+		build(&roots[i])
+	}
+
+	if roots == nil {
+		roots = []models.Category{}
+	}
+
+	return roots
+}
@@ -0,0 +1,477 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/policies"
+	"boilerplate/app/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexComment godoc
+// @Summary      Get post comments
+// @Description  Get the comment threads of a post as a tree, pages are counted in top level comments
+// @Tags         Comment
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Param	 id path int true "Post ID"
+// @Param	 page query int true "Page number" default(1)
+// @Param	 perPage query int true "Number of threads per page" default(10)
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.Comment
+// @Failure      404  {object}  utils.Response
+// @Router       /posts/{id}/comments [get]
+func IndexComment(c *fiber.Ctx) error {
+	// get query params page and perPage
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("perPage", "10"))
+
+	// Comments of unpublished posts are hidden with the post
+	user, _ := middlewares.FindUserByToken(c)
+
+	post, err := findVisiblePost(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "Post not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Get a page of threads, then all their replies at once
+	var roots []models.Comment
+	db.DB.Where("post_id = ? AND parent_id IS NULL", post.ID).
+		Scopes(policies.VisibleComments(user, post), db.Paginate(page, perPage)).
+		Order("id asc").
+		Find(&roots)
+
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	var replies []models.Comment
+	if len(rootIDs) > 0 {
+		db.DB.Where("post_id = ? AND root_id IN ?", post.ID, rootIDs).
+			Scopes(policies.VisibleComments(user, post)).
+			Order("id asc").
+			Find(&replies)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched comments",
+		Data:    commentTree(roots, replies),
+	})
+}
+
+// StoreComment godoc
+// @Summary      Create comment
+// @Description  Comment on a published post, or reply to a comment up to the maximum depth
+// @Tags         Comment
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Param        request body models.CreateCommentRequest true "Create comment request"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Comment
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/comments [post]
+func StoreComment(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	post, err := findVisiblePost(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "Post not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	if post.Status != models.PostStatusPublished {
+		return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+			Status:  false,
+			Message: "Comments are closed until the post is published",
+			Data:    []interface{}{},
+		})
+	}
+
+	// get and parse request body
+	commentRequest := new(models.CreateCommentRequest)
+	if err := c.BodyParser(commentRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(commentRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	comment := models.Comment{
+		PostID: post.ID,
+		UserID: &user.ID,
+		Body:   commentRequest.Body,
+	}
+
+	// A reply joins the thread of its parent one level deeper
+	if commentRequest.ParentID != nil {
+		var parent models.Comment
+		if err := db.DB.Where("id = ? AND post_id = ? AND hidden_at IS NULL AND removed_at IS NULL", *commentRequest.ParentID, post.ID).First(&parent).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+				Status:  false,
+				Message: "Parent comment not found",
+				Data:    []interface{}{},
+			})
+		}
+
+		if parent.Depth >= config.COMMENT_MAX_DEPTH {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+				Status:  false,
+				Message: fmt.Sprintf("Replies can't be nested more than %d levels deep", config.COMMENT_MAX_DEPTH),
+				Data:    []interface{}{},
+			})
+		}
+
+		comment.ParentID = &parent.ID
+		comment.RootID = parent.RootID
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+		comment.Depth = parent.Depth + 1
+	}
+
+	if err := db.DB.Create(&comment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to create comment",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully created comment",
+		Data:    comment,
+	})
+}
+
+// UpdateComment godoc
+// @Summary      Update comment
+// @Description  Edit a comment of the current user
+// @Tags         Comment
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param	 id path int true "Comment ID"
+// @Param        request body models.UpdateCommentRequest true "Update comment request"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Comment
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /comments/{id} [put]
+func UpdateComment(c *fiber.Ctx) error {
+	// find comment by id, only its author can edit it
+	comment, status, message := findAuthorizedComment(c)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	// get and parse request body
+	commentRequest := new(models.UpdateCommentRequest)
+	if err := c.BodyParser(commentRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// validate user input
+	validationErrors := utils.GlobalValidator.Validate(commentRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// update comment
+	if err := db.DB.Model(&comment).Update("body", commentRequest.Body).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to update comment",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully updated comment",
+		Data:    comment,
+	})
+}
+
+// DestroyComment godoc
+// @Summary      Delete comment
+// @Description  Delete a comment of the current user, a comment with replies is emptied so the thread stays readable
+// @Tags         Comment
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Comment ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /comments/{id} [delete]
+func DestroyComment(c *fiber.Ctx) error {
+	// find comment by id, only its author can delete it
+	comment, status, message := findAuthorizedComment(c)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	var replies int64
+	db.DB.Model(&models.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies)
+
+	var err error
+	if replies > 0 {
+		err = db.DB.Model(&comment).Updates(map[string]interface{}{"body": "", "removed_at": time.Now()}).Error
+	} else {
+		err = db.DB.Delete(&comment).Error
+	}
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to delete comment",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully deleted comment",
+		Data:    map[string]interface{}{},
+	})
+}
+
+// HideComment godoc
+// @Summary      Hide comment
+// @Description  Hide a comment and its replies from readers, the post author and editors still see it
+// @Tags         Comment
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Comment ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Comment
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /comments/{id}/hide [post]
+func HideComment(c *fiber.Ctx) error {
+	now := time.Now()
+	return moderateComment(c, &now)
+}
+
+// UnhideComment godoc
+// @Summary      Unhide comment
+// @Description  Show a hidden comment to readers again
+// @Tags         Comment
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Comment ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Comment
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /comments/{id}/unhide [post]
+func UnhideComment(c *fiber.Ctx) error {
+	return moderateComment(c, nil)
+}
+
+// moderateComment sets the hidden date of the comment when the caller moderates its post
+func moderateComment(c *fiber.Ctx, hiddenAt *time.Time) error {
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	var comment models.Comment
+	var post models.Post
+	if err := db.DB.First(&comment, c.Params("id")).Error; err != nil || db.DB.First(&post, comment.PostID).Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "Comment not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	if !policies.CanModerateComments(user, post) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+			Status:  false,
+			Message: "You are not allowed to moderate this comment",
+			Data:    []interface{}{},
+		})
+	}
+
+	comment.HiddenAt = hiddenAt
+	if err := db.DB.Model(&comment).Update("hidden_at", hiddenAt).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to update comment",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully updated comment",
+		Data:    comment,
+	})
+}
+
+// findAuthorizedComment loads the comment from the ":id" param and checks the caller is
+// its author, returning the status and message to respond with when it is not fiber.StatusOK
+func findAuthorizedComment(c *fiber.Ctx) (models.Comment, int, string) {
+	var comment models.Comment
+	if err := db.DB.First(&comment, c.Params("id")).Error; err != nil {
+		return comment, fiber.StatusNotFound, "Comment not found"
+	}
+
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return comment, fiber.StatusUnauthorized, "Unauthorized"
+	}
+
+	if !policies.CanUpdateComment(user, comment) {
+		return comment, fiber.StatusForbidden, "You are not allowed to modify this comment"
+	}
+
+	return comment, fiber.StatusOK, ""
+}
+
+// commentTree nests the replies under their parents, replies whose parent was left
+// out (hidden) are left out with it
+func commentTree(roots []models.Comment, replies []models.Comment) []models.Comment {
+	children := map[uint][]models.Comment{}
+	for _, reply := range replies {
+		children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+	}
+
+	var build func(comment *models.Comment)
+	build = func(comment *models.Comment) {
+		comment.Replies = children[comment.ID]
+		if comment.Replies == nil {
+			comment.Replies = []models.Comment{}
+		}
+
+		for i := range comment.Replies {
+			build(&comment.Replies[i])
+		}
+	}
+
+	for i := range roots {
+		build(&roots[i])
+	}
+
+	if roots == nil {
+		roots = []models.Comment{}
+	}
+
+	return roots
+}
+
+// withCommentCounts fills the number of visible comments of each post in one query
+func withCommentCounts(posts []models.Post) {
+	if len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	var counts []struct {
+		PostID uint
+		Count  int64
+	}
+	db.DB.Model(&models.Comment{}).
+		Select("post_id, COUNT(*) AS count").
+		Where("post_id IN ? AND hidden_at IS NULL AND removed_at IS NULL", ids).
+		Group("post_id").
+		Scan(&counts)
+
+	commentCounts := map[uint]int64{}
+	for _, count := range counts {
+		commentCounts[count.PostID] = count.Count
+	}
+
+	for i := range posts {
+		posts[i].CommentCount = commentCounts[posts[i].ID]
+	}
+}
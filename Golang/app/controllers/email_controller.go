@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/mailer"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const emailVerificationPurpose = "verify_email"
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Mark the email address as verified using the token from the verification link, a link sent for a pending email change switches to the new address
+// @Tags         Email
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.VerifyEmailRequest true "Verify email request"
+// @Success      200  {object}  models.Profile
+// @Failure      400  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /email/verify [post]
+func VerifyEmail(c *fiber.Ctx) error {
+	// Get and parse user input
+	verifyRequest := new(models.VerifyEmailRequest)
+	if err := c.BodyParser(verifyRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(verifyRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check token signature and expiry
+	claims, err := middlewares.ParsePurposeJWT(verifyRequest.Token, emailVerificationPurpose)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired verification link",
+			Data:    nil,
+		})
+	}
+
+	// The link is only valid for the address it was sent to
+	email, _ := claims["email"].(string)
+
+	var user models.User
+	if err := pg.DB.Where("id = ?", claims["user_id"]).First(&user).Error; err != nil || email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired verification link",
+			Data:    nil,
+		})
+	}
+
+	switch email {
+	case user.Email:
+		// Mark as verified
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+
+			if err := pg.DB.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+					Status:  false,
+					Message: "Could not verify email",
+					Data:    nil,
+				})
+			}
+		}
+
+	case user.PendingEmail:
+		// Someone may have registered the address in the meantime
+		if emailTaken(email, user.ID) {
+			return c.Status(fiber.StatusConflict).JSON(utils.Response{
+				Status:  false,
+				Message: "Email is already registered",
+				Data:    nil,
+			})
+		}
+
+		// Switch to the new address
+		oldEmail := user.Email
+		now := time.Now()
+		user.Email = email
+		user.PendingEmail = ""
+		user.EmailVerifiedAt = &now
+
+		if err := pg.DB.Model(&user).Updates(map[string]interface{}{
+			"email":             user.Email,
+			"pending_email":     "",
+			"email_verified_at": now,
+		}).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+				Status:  false,
+				Message: "Could not verify email",
+				Data:    nil,
+			})
+		}
+
+		// Let the previous address know, in case the change wasn't wanted
+		if err := mailer.Send(oldEmail, "Your email address was changed", fmt.Sprintf(
+			"Hi %s,\n\nThe email address of your account was changed to %s. If you did not do this, please contact us.",
+			user.Name, user.Email,
+		)); err != nil {
+			log.Printf("email change notice for user %d: %v", user.ID, err)
+		}
+
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired verification link",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Email verified successfully",
+		Data:    user.Profile(),
+	})
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification link to the current user email address
+// @Tags         Email
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /email/resend [post]
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Email is already verified",
+			Data:    nil,
+		})
+	}
+
+	if err := sendVerificationEmail(*user, user.Email); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not send verification email",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Verification email sent",
+		Data:    nil,
+	})
+}
+
+// ChangeEmail godoc
+// @Summary      Change email address
+// @Description  Send a verification link to the new address, the email is only switched once it is verified
+// @Tags         Email
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.ChangeEmailRequest true "Change email request"
+// @Success      200  {object}  models.Profile
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/email [put]
+func ChangeEmail(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	changeRequest := new(models.ChangeEmailRequest)
+	if err := c.BodyParser(changeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	changeRequest.Email = utils.NormalizeEmail(changeRequest.Email)
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(changeRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check password
+	if !confirmUser(*user, changeRequest.Password, changeRequest.ConfirmationToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid password or confirmation token",
+			Data:    nil,
+		})
+	}
+
+	if changeRequest.Email == user.Email {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "This is already your email address",
+			Data:    nil,
+		})
+	}
+
+	// Check if email is already registered
+	if emailTaken(changeRequest.Email, user.ID) {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Email is already registered",
+			Data:    nil,
+		})
+	}
+
+	// Keep the new address pending until it is verified
+	user.PendingEmail = changeRequest.Email
+	if err := pg.DB.Model(user).Update("pending_email", user.PendingEmail).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change email",
+			Data:    nil,
+		})
+	}
+
+	if err := sendVerificationEmail(*user, user.PendingEmail); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not send verification email",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Verification email sent to the new address",
+		Data:    user.Profile(),
+	})
+}
+
+// emailTaken reports whether another user than exceptID already uses the email,
+// accounts waiting to be purged keep their address until then
+func emailTaken(email string, exceptID uint) bool {
+	var count int64
+	pg.DB.Unscoped().Model(&models.User{}).Where("lower(email) = ? AND id <> ?", utils.NormalizeEmail(email), exceptID).Count(&count)
+
+	return count > 0
+}
+
+// sendVerificationEmail sends a verification link for the given address of the user,
+// either the current one or a pending email change
+func sendVerificationEmail(user models.User, email string) error {
+	token, err := middlewares.GeneratePurposeJWT(user.ID, emailVerificationPurpose, config.EMAIL_VERIFICATION_TTL, jwt.MapClaims{
+		"email": email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := config.APP_URL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return mailer.Send(email, "Verify your email address", fmt.Sprintf(
+		"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s",
+		user.Name, config.EMAIL_VERIFICATION_TTL, link,
+	))
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify access tokens, empty when tokens are signed with a shared secret
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  map[string][]middlewares.JWK
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"keys": middlewares.JWKS(),
+	})
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/mailer"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestMagicLink godoc
+// @Summary      Request a login link
+// @Description  Send a single-use login link if the email is registered, the response is the same either way
+// @Tags         Auth
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.MagicLinkRequest true "Magic link request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Router       /login/magic/request [post]
+func RequestMagicLink(c *fiber.Ctx) error {
+	// Get and parse user input
+	magicRequest := new(models.MagicLinkRequest)
+	if err := c.BodyParser(magicRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	magicRequest.Email = utils.NormalizeEmail(magicRequest.Email)
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(magicRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Only send the link when the user exists, but never tell the caller
+	var user models.User
+	if err := pg.DB.Where("lower(email) = ?", magicRequest.Email).First(&user).Error; err == nil {
+		if err := sendMagicLinkEmail(user); err != nil {
+			log.Printf("magic link for user %d: %v", user.ID, err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "If the email is registered, a login link has been sent",
+		Data:    nil,
+	})
+}
+
+// LoginMagicLink godoc
+// @Summary      Login with a magic link
+// @Description  Redeem a login link, returns an MFA token instead when two-factor authentication is enabled
+// @Tags         Auth
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.MagicLoginRequest true "Magic login request"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /login/magic [post]
+func LoginMagicLink(c *fiber.Ctx) error {
+	// Get and parse user input
+	loginRequest := new(models.MagicLoginRequest)
+	if err := c.BodyParser(loginRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(loginRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Redeem login token
+	user, err := middlewares.ConsumeOneTimeToken(loginRequest.Token, models.TokenPurposeMagicLink)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired login link",
+			Data:    nil,
+		})
+	}
+
+	// Opening the link proves the user owns the address
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+
+		if err := pg.DB.Model(user).Update("email_verified_at", now).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+				Status:  false,
+				Message: "Could not login user",
+				Data:    nil,
+			})
+		}
+	}
+
+	return completeLogin(c, *user)
+}
+
+func sendMagicLinkEmail(user models.User) error {
+	token, err := middlewares.IssueOneTimeToken(user.ID, models.TokenPurposeMagicLink, config.MAGIC_LINK_TTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.APP_URL + "/login/magic?token=" + url.QueryEscape(token)
+
+	return mailer.Send(user.Email, "Your login link", fmt.Sprintf(
+		"Hi %s,\n\nUse the link below to login. It can be used once and expires in %s.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+		user.Name, config.MAGIC_LINK_TTL, link,
+	))
+}
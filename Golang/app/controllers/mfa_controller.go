@@ -0,0 +1,487 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaPurpose        = "mfa"
+	recoveryCodeCount = 10
+)
+
+// EnrollMfa godoc
+// @Summary      Start two-factor enrollment
+// @Description  Generate a new TOTP secret and otpauth URI, two-factor is enabled once a code is confirmed
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  models.MfaEnrollResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/mfa/enroll [post]
+func EnrollMfa(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	if user.TwoFactorEnabledAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Two-factor authentication is already enabled",
+			Data:    nil,
+		})
+	}
+
+	// Generate and store the pending secret
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not enroll two-factor authentication",
+			Data:    nil,
+		})
+	}
+
+	if err := pg.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not enroll two-factor authentication",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Scan the QR code and confirm with a code to enable two-factor authentication",
+		Data: models.MfaEnrollResponse{
+			Secret:     secret,
+			OtpauthURI: utils.TOTPURI(config.APP_NAME, user.Email, secret),
+		},
+	})
+}
+
+// ConfirmMfa godoc
+// @Summary      Confirm two-factor enrollment
+// @Description  Enable two-factor authentication with a code from the authenticator app and return recovery codes
+// @Tags         MFA
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.MfaCodeRequest true "MFA code request"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/mfa/confirm [post]
+func ConfirmMfa(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	codeRequest := new(models.MfaCodeRequest)
+	if err := c.BodyParser(codeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(codeRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	if user.TwoFactorEnabledAt != nil || user.TOTPSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "No pending two-factor enrollment",
+			Data:    nil,
+		})
+	}
+
+	if !acceptTOTP(*user, codeRequest.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid two-factor code",
+			Data:    nil,
+		})
+	}
+
+	// Enable two-factor and hand out recovery codes
+	var codes []string
+	err = pg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("two_factor_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		codes, err = regenerateRecoveryCodes(tx, user.ID)
+		return err
+	})
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not enable two-factor authentication",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Two-factor authentication enabled, store the recovery codes somewhere safe",
+		Data:    models.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// DisableMfa godoc
+// @Summary      Disable two-factor authentication
+// @Description  Disable two-factor authentication, requires the password and a code or recovery code
+// @Tags         MFA
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.MfaDisableRequest true "MFA disable request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/mfa/disable [post]
+func DisableMfa(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	disableRequest := new(models.MfaDisableRequest)
+	if err := c.BodyParser(disableRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(disableRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	if user.TwoFactorEnabledAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Two-factor authentication is not enabled",
+			Data:    nil,
+		})
+	}
+
+	// Check password and second factor
+	if !confirmUser(*user, disableRequest.Password, disableRequest.ConfirmationToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid password or confirmation token",
+			Data:    nil,
+		})
+	}
+
+	if !verifySecondFactor(*user, disableRequest.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid two-factor code",
+			Data:    nil,
+		})
+	}
+
+	// Disable two-factor and drop recovery codes
+	err = pg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":           "",
+			"two_factor_enabled_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not disable two-factor authentication",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Two-factor authentication disabled",
+		Data:    nil,
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace all recovery codes, requires a current two-factor code
+// @Tags         MFA
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.MfaCodeRequest true "MFA code request"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	codeRequest := new(models.MfaCodeRequest)
+	if err := c.BodyParser(codeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(codeRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	if user.TwoFactorEnabledAt == nil || !acceptTOTP(*user, codeRequest.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid two-factor code",
+			Data:    nil,
+		})
+	}
+
+	codes, err := regenerateRecoveryCodes(pg.DB, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not regenerate recovery codes",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Recovery codes regenerated",
+		Data:    models.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// LoginMfa godoc
+// @Summary      Complete login with two-factor
+// @Description  Exchange the MFA token returned by login and a TOTP or recovery code for the access and refresh tokens
+// @Tags         Auth
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.MfaLoginRequest true "MFA login request"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      429  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /login/mfa [post]
+func LoginMfa(c *fiber.Ctx) error {
+	// Get and parse user input
+	mfaRequest := new(models.MfaLoginRequest)
+	if err := c.BodyParser(mfaRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(mfaRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check MFA token
+	claims, err := middlewares.ParsePurposeJWT(mfaRequest.MfaToken, mfaPurpose)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired MFA token",
+			Data:    nil,
+		})
+	}
+
+	var user models.User
+	if err := pg.DB.Where("id = ?", claims["user_id"]).First(&user).Error; err != nil || user.TwoFactorEnabledAt == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired MFA token",
+			Data:    nil,
+		})
+	}
+
+	// Codes are throttled like passwords
+	if wait := middlewares.LoginRetryAfter(user.Email, c.IP()); wait > 0 {
+		return tooManyLoginAttempts(c, wait)
+	}
+
+	// Check second factor
+	if !verifySecondFactor(user, mfaRequest.Code) {
+		middlewares.RecordLoginFailure(user.Email, c.IP(), &user.ID, "invalid_mfa_code")
+
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid two-factor code",
+			Data:    nil,
+		})
+	}
+
+	middlewares.RecordLoginSuccess(user.Email, c.IP(), user.ID)
+
+	return issueLoginTokens(c, user)
+}
+
+// mfaChallenge answers a correct password with a short-lived MFA token
+func mfaChallenge(c *fiber.Ctx, user models.User) error {
+	token, err := middlewares.GeneratePurposeJWT(user.ID, mfaPurpose, config.MFA_TOKEN_TTL, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login user",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Two-factor authentication required",
+		Data: models.MfaRequiredResponse{
+			MfaRequired: true,
+			MfaToken:    token,
+		},
+	})
+}
+
+// verifySecondFactor accepts a TOTP code or consumes an unused recovery code
+func verifySecondFactor(user models.User, code string) bool {
+	code = strings.TrimSpace(code)
+
+	if acceptTOTP(user, code) {
+		return true
+	}
+
+	res := pg.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(strings.ToLower(code))).
+		Update("used_at", time.Now())
+
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+// acceptTOTP checks a code of the user's authenticator and records its time step, each code
+// is accepted once and codes older than the last accepted one are refused
+func acceptTOTP(user models.User, code string) bool {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false
+	}
+
+	// Conditional update so the same code can't be redeemed twice concurrently
+	res := pg.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+func regenerateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		raw := hex.EncodeToString(b)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Introspect godoc
+// @Summary      Introspect token
+// @Description  Tell a service whether an access or refresh token is active, with its subject, expiry and scopes (RFC 7662). The client authenticates with HTTP Basic or client_id and client_secret form fields.
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param	 token formData string true "Token"
+// @Param	 token_type_hint formData string false "access_token or refresh_token"
+// @Success      200  {object}  models.IntrospectionResponse
+// @Failure      400  {object}  models.OAuthError
+// @Failure      401  {object}  models.OAuthError
+// @Router       /oauth/introspect [post]
+func Introspect(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	token := c.FormValue("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.OAuthError{
+			Error:            "invalid_request",
+			ErrorDescription: "The token parameter is required",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(middlewares.IntrospectToken(token, c.FormValue("token_type_hint")))
+}
+
+// Revoke godoc
+// @Summary      Revoke token
+// @Description  Revoke the session of an access or refresh token (RFC 7009), unknown tokens are ignored. The client authenticates with HTTP Basic or client_id and client_secret form fields.
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Param	 token formData string true "Token"
+// @Param	 token_type_hint formData string false "access_token or refresh_token"
+// @Success      200
+// @Failure      400  {object}  models.OAuthError
+// @Failure      401  {object}  models.OAuthError
+// @Failure      503  {object}  models.OAuthError
+// @Router       /oauth/revoke [post]
+func Revoke(c *fiber.Ctx) error {
+	token := c.FormValue("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.OAuthError{
+			Error:            "invalid_request",
+			ErrorDescription: "The token parameter is required",
+		})
+	}
+
+	if err := middlewares.RevokeToken(token, c.FormValue("token_type_hint")); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.OAuthError{
+			Error:            "temporarily_unavailable",
+			ErrorDescription: "Could not revoke token",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
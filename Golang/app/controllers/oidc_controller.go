@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/oidc"
+	"boilerplate/app/utils"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const oidcStateCookie = "oidcState"
+
+var errOidcEmailNotVerified = errors.New("provider email is missing or not verified")
+
+// OidcLogin godoc
+// @Summary      Login with OpenID Connect
+// @Description  Redirect to the configured OpenID Connect provider (authorization code + PKCE)
+// @Tags         Auth
+// @Success      302
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /auth/oidc/login [get]
+func OidcLogin(c *fiber.Ctx) error {
+	if !oidc.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "OpenID Connect login is not configured",
+			Data:    nil,
+		})
+	}
+
+	// Generate state and PKCE verifier, kept in a short-lived cookie until the callback
+	state, err := utils.RandomToken(16)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not start login",
+			Data:    nil,
+		})
+	}
+
+	verifier, err := utils.RandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not start login",
+			Data:    nil,
+		})
+	}
+
+	// Lax whatever the configuration, the provider redirects back cross-site
+	cookie := middlewares.SecureCookie(oidcStateCookie, state+"."+verifier, time.Now().Add(time.Minute*10), true)
+	cookie.SameSite = fiber.CookieSameSiteLaxMode
+	c.Cookie(cookie)
+
+	return c.Redirect(oidc.AuthCodeURL(state, verifier), fiber.StatusFound)
+}
+
+// OidcCallback godoc
+// @Summary      OpenID Connect callback
+// @Description  Exchange the authorization code, then login the linked user, link a user with the same verified email or register a new one
+// @Tags         Auth
+// @Produce      json
+// @Param	 code query string true "Authorization code"
+// @Param	 state query string true "State"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /auth/oidc/callback [get]
+func OidcCallback(c *fiber.Ctx) error {
+	if !oidc.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "OpenID Connect login is not configured",
+			Data:    nil,
+		})
+	}
+
+	// Check state against the cookie set by OidcLogin
+	state, verifier, _ := strings.Cut(c.Cookies(oidcStateCookie), ".")
+	c.Cookie(middlewares.SecureCookie(oidcStateCookie, "", time.Now().Add(-time.Hour), true))
+
+	if state == "" || verifier == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid login state",
+			Data:    nil,
+		})
+	}
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Login was cancelled or denied",
+			Data:    c.Query("error"),
+		})
+	}
+
+	// Exchange code and read the user claims
+	token, err := oidc.Exchange(c.Query("code"), verifier)
+	if err != nil {
+		log.Printf("oidc exchange: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login with provider",
+			Data:    nil,
+		})
+	}
+
+	info, err := oidc.FetchUserInfo(token)
+	if err != nil {
+		log.Printf("oidc userinfo: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login with provider",
+			Data:    nil,
+		})
+	}
+
+	user, err := findOrLinkOidcUser(*info)
+	if errors.Is(err, errOidcEmailNotVerified) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "The provider did not return a verified email",
+			Data:    nil,
+		})
+	}
+
+	if err != nil {
+		log.Printf("oidc link: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not login with provider",
+			Data:    nil,
+		})
+	}
+
+	return completeLogin(c, *user)
+}
+
+// IndexIdentity godoc
+// @Summary      Get linked identities
+// @Description  Get the external provider accounts linked to the current user
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.UserIdentity
+// @Failure      401  {object}  utils.Response
+// @Router       /me/identities [get]
+func IndexIdentity(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	var identities []models.UserIdentity
+	pg.DB.Where("user_id = ?", user.ID).Order("id asc").Find(&identities)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched identities",
+		Data:    identities,
+	})
+}
+
+// DestroyIdentity godoc
+// @Summary      Unlink identity
+// @Description  Unlink an external provider account, the last sign-in method can't be removed
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Identity ID"
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/identities/{id} [delete]
+func DestroyIdentity(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	var identity models.UserIdentity
+	if err := pg.DB.Where("id = ? AND user_id = ?", c.Params("id"), user.ID).First(&identity).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "Identity not found",
+			Data:    nil,
+		})
+	}
+
+	// Keep at least one way to sign in
+	var count int64
+	pg.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count)
+	if user.PasswordHash == "" && count <= 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Set a password before unlinking your last identity",
+			Data:    nil,
+		})
+	}
+
+	if err := pg.DB.Delete(&identity).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not unlink identity",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Identity unlinked successfully",
+		Data:    nil,
+	})
+}
+
+// findOrLinkOidcUser resolves the provider account to a user, linking by verified
+// email or registering a new user when no identity exists yet
+func findOrLinkOidcUser(info oidc.UserInfo) (*models.User, error) {
+	var identity models.UserIdentity
+	err := pg.DB.Where("provider = ? AND subject = ?", config.OIDC_PROVIDER, info.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := pg.DB.Where("id = ?", identity.UserID).First(&user).Error; err != nil {
+			return nil, err
+		}
+
+		return &user, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Only a verified email is trusted to link or create an account
+	if info.Email == "" || !info.EmailVerified {
+		return nil, errOidcEmailNotVerified
+	}
+
+	email := utils.NormalizeEmail(info.Email)
+	var user models.User
+	takeover := false
+	err = pg.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("lower(email) = ?", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var role models.Role
+			if err := tx.Where("name = ?", models.RoleAuthor).First(&role).Error; err != nil {
+				return err
+			}
+
+			name := info.Name
+			if name == "" {
+				name, _, _ = strings.Cut(email, "@")
+			}
+
+			now := time.Now()
+			user = models.User{
+				Name:            name,
+				Email:           email,
+				EmailVerifiedAt: &now,
+				Roles:           []models.Role{role},
+			}
+			err = tx.Create(&user).Error
+		} else if err == nil && user.EmailVerifiedAt == nil {
+			// Nobody proved owning this address before, whoever registered it may not be
+			// the provider's user: drop the credentials they set up before linking
+			takeover = true
+			err = resetUnverifiedAccount(tx, &user)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: config.OIDC_PROVIDER,
+			Subject:  info.Subject,
+			Email:    info.Email,
+		}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if takeover {
+		if err := middlewares.RevokeAllSessions(user.ID); err != nil {
+			return nil, err
+		}
+
+		// reload for the new token version
+		if err := pg.DB.Where("id = ?", user.ID).First(&user).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// resetUnverifiedAccount verifies the email of the user and removes the password, second
+// factor and API keys that were set up while the address was unverified
+func resetUnverifiedAccount(tx *gorm.DB, user *models.User) error {
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.PasswordHash = ""
+	user.TOTPSecret = ""
+	user.TwoFactorEnabledAt = nil
+
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"email_verified_at":     now,
+		"password_hash":         "",
+		"totp_secret":           "",
+		"two_factor_enabled_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.ApiKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now).Error
+}
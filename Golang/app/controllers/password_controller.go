@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	pg "boilerplate/app/db"
+	"boilerplate/app/hasher"
+	"boilerplate/app/mailer"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/policies"
+	"boilerplate/app/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Send a password reset link if the email is registered, the response is the same either way
+// @Tags         Password
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.ForgotPasswordRequest true "Forgot password request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Router       /password/forgot [post]
+func ForgotPassword(c *fiber.Ctx) error {
+	// Get and parse user input
+	forgotRequest := new(models.ForgotPasswordRequest)
+	if err := c.BodyParser(forgotRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	forgotRequest.Email = utils.NormalizeEmail(forgotRequest.Email)
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(forgotRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Only send the link when the user exists, but never tell the caller
+	var user models.User
+	if err := pg.DB.Where("lower(email) = ?", forgotRequest.Email).First(&user).Error; err == nil {
+		if err := sendPasswordResetEmail(user); err != nil {
+			log.Printf("password reset for user %d: %v", user.ID, err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "If the email is registered, a password reset link has been sent",
+		Data:    nil,
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password using a password reset token, all sessions are logged out
+// @Tags         Password
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param        request body models.ResetPasswordRequest true "Reset password request"
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /password/reset [post]
+func ResetPassword(c *fiber.Ctx) error {
+	// Get and parse user input
+	resetRequest := new(models.ResetPasswordRequest)
+	if err := c.BodyParser(resetRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(resetRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check reset token, it is only redeemed once the new password is accepted
+	user, err := middlewares.FindOneTimeToken(resetRequest.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired reset token",
+			Data:    nil,
+		})
+	}
+
+	// Check password policy
+	if policyErrors := policies.CheckPassword(resetRequest.Password, user.Email, user.Name); len(policyErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    policyErrors,
+		})
+	}
+
+	// Redeem reset token
+	user, err = middlewares.ConsumeOneTimeToken(resetRequest.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid or expired reset token",
+			Data:    nil,
+		})
+	}
+
+	// Generate password
+	hashedPassword, err := hasher.Hash(resetRequest.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not reset password",
+			Data:    nil,
+		})
+	}
+
+	// Save password
+	if err := pg.DB.Model(user).Update("password_hash", hashedPassword).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not reset password",
+			Data:    nil,
+		})
+	}
+
+	// Log out every session that may have used the old password
+	if err := middlewares.RevokeAllSessions(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not reset password",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Password reset successfully",
+		Data:    nil,
+	})
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the current user password, every other session is logged out and new tokens are returned
+// @Tags         Password
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Param        request body models.ChangePasswordRequest true "Change password request"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/password [put]
+func ChangePassword(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Get and parse user input
+	changeRequest := new(models.ChangePasswordRequest)
+	if err := c.BodyParser(changeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    nil,
+		})
+	}
+
+	// Validate user input
+	validationErrors := utils.GlobalValidator.Validate(changeRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// Check current password, accounts without one set their first password
+	if !confirmUser(*user, changeRequest.CurrentPassword, changeRequest.ConfirmationToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid password or confirmation token",
+			Data:    nil,
+		})
+	}
+
+	// Check password policy
+	if policyErrors := policies.CheckPassword(changeRequest.Password, user.Email, user.Name); len(policyErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    policyErrors,
+		})
+	}
+
+	// Generate password
+	hashedPassword, err := hasher.Hash(changeRequest.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	// Save password
+	if err := pg.DB.Model(user).Update("password_hash", hashedPassword).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	// Log out every session, this one gets new tokens below
+	if err := middlewares.RevokeAllSessions(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	// Reload user for the new token version
+	if err := pg.DB.First(user, user.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	// Generate JWT
+	token, err := middlewares.GenerateJWT(*user, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	// explode token with | as delimiter
+	tokenList := strings.Split(token, "|")
+
+	// set cookie
+	if err := setRefreshCookie(c, tokenList[1]); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not change password",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Password changed successfully",
+		Data: models.LoginResponse{
+			Token:        tokenList[0],
+			RefreshToken: tokenList[1],
+		},
+	})
+}
+
+func sendPasswordResetEmail(user models.User) error {
+	token, err := middlewares.IssueOneTimeToken(user.ID, models.TokenPurposePasswordReset, config.PASSWORD_RESET_TTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.APP_URL + "/reset-password?token=" + url.QueryEscape(token)
+
+	return mailer.Send(user.Email, "Reset your password", fmt.Sprintf(
+		"Hi %s,\n\nUse the link below to reset your password. It expires in %s.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+		user.Name, config.PASSWORD_RESET_TTL, link,
+	))
+}
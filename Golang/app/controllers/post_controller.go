@@ -4,19 +4,24 @@ import (
 	"boilerplate/app/db"
 	"boilerplate/app/middlewares"
 	"boilerplate/app/models"
+	"boilerplate/app/policies"
 	"boilerplate/app/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 	"strconv"
 )
 
 // IndexPost godoc
 // @Summary      Get all posts
-// @Description  Get all posts and return them as a json
+// @Description  Get all posts and return them as a json, unpublished posts are only listed for their author and editors
 // @Tags         Post
 // @Accept       json
 // @Headers      Content-Type application/json
 // @Param	 page query int true "Page number" default(1)
 // @Param	 perPage query int true "Number of posts per page" default(10)
+// @Param	 status query string false "Filter by status (draft, in_review, scheduled, published, archived)"
+// @Param	 tag query string false "Filter by tag slug"
+// @Param	 category query string false "Filter by category slug, including its subcategories"
 // @Produce      json
 // @Security	 ApiKeyAuth
 // @Success      200  {array}   models.Post
@@ -29,9 +34,80 @@ func IndexPost(c *fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	perPage, _ := strconv.Atoi(c.Query("perPage", "10"))
 
+	// Get the viewer if any, anonymous users only see published posts
+	user, _ := middlewares.FindUserByToken(c)
+
+	query := db.DB.Scopes(policies.VisiblePosts(user))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Scopes(postsTagged(tag))
+	}
+
+	if category := c.Query("category"); category != "" {
+		query = query.Scopes(postsInCategory(category))
+	}
+
 	// Get all posts and paginate
 	var posts []models.Post
-	db.DB.Scopes(db.Paginate(page, perPage)).Order("id desc").Find(&posts)
+	query.Preload("Category").Preload("Tags").Scopes(db.Paginate(page, perPage)).Order("id desc").Find(&posts)
+	withCommentCounts(posts)
+	withReactions(posts, user)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched posts",
+		Data:    posts,
+	})
+}
+
+// IndexMyPost godoc
+// @Summary      Get my posts
+// @Description  Get the posts of the current user whatever their status, including drafts and scheduled posts
+// @Tags         Post
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Param	 page query int true "Page number" default(1)
+// @Param	 perPage query int true "Number of posts per page" default(10)
+// @Param	 status query string false "Only posts with this status (draft, in_review, scheduled, published, archived)"
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {array}   models.Post
+// @Failure      401  {object}  utils.Response
+// @Router       /me/posts [get]
+func IndexMyPost(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    []interface{}{},
+		})
+	}
+
+	// get query params page and perPage
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("perPage", "10"))
+
+	query := db.DB.Where("user_id = ?", user.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Scheduled posts come in publication order, the others newest first
+	order := "id desc"
+	if c.Query("status") == models.PostStatusScheduled {
+		order = "publish_at asc"
+	}
+
+	var posts []models.Post
+	query.Preload("Category").Preload("Tags").Scopes(db.Paginate(page, perPage)).Order(order).Find(&posts)
+	withCommentCounts(posts)
+	withReactions(posts, user)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
@@ -55,10 +131,11 @@ func IndexPost(c *fiber.Ctx) error {
 // @Failure      500  {object}  utils.Response
 // @Router       /posts/{id} [get]
 func ShowPost(c *fiber.Ctx) error {
-	var post models.Post
+	// Check if post exists, posts the viewer may not see are hidden as if they didn't exist
+	user, _ := middlewares.FindUserByToken(c)
 
-	// Check if post exists
-	if err := db.DB.First(&post, c.Params("id")).Error; err != nil {
+	var post models.Post
+	if err := db.DB.Scopes(policies.VisiblePosts(user)).Preload("Category").Preload("Tags").First(&post, c.Params("id")).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
 			Status:  false,
 			Message: "Post not found",
@@ -66,23 +143,26 @@ func ShowPost(c *fiber.Ctx) error {
 		})
 	}
 
-	db.DB.Where("id = ?", c.Params("id")).First(&post)
+	posts := []models.Post{post}
+	withCommentCounts(posts)
+	withReactions(posts, user)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
 		Message: "Successfully fetched post",
-		Data:    post,
+		Data:    posts[0],
 	})
 }
 
 // StorePost godoc
 // @Summary	 Create new post
-// @Description  Create a new post and return it
+// @Description  Create a new draft post and return it
 // @Tags         Post
 // @Accept       json
 // @Headers      Content-Type application/json
 // @Produce      json
 // @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
 // @Success      200  {array}   models.Post
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
@@ -112,7 +192,7 @@ func StorePost(c *fiber.Ctx) error {
 	// Get authenticated user from token
 	user, err := middlewares.FindUserByToken(c)
 
-	if err != nil {
+	if err != nil || user == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
 			Status:  false,
 			Message: "Unauthorized",
@@ -120,14 +200,38 @@ func StorePost(c *fiber.Ctx) error {
 		})
 	}
 
+	if postRequest.CategoryID != nil && !categoryExists(*postRequest.CategoryID) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Category not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	tags, err := findOrCreateTags(db.DB, postRequest.Tags)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to create post",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
 	// Create new post
 	post := models.Post{
-		Title: postRequest.Title,
-		Body:  postRequest.Body,
-		User:  user,
+		Title:      postRequest.Title,
+		Body:       postRequest.Body,
+		Status:     models.PostStatusDraft,
+		UserID:     user.ID,
+		User:       user,
+		CategoryID: postRequest.CategoryID,
+		Tags:       tags,
 	}
 
-	if err := db.DB.Create(&post).Error; err != nil {
+	// the owner is set explicitly, the user record itself is not upserted
+	if err := db.DB.Omit("User").Create(&post).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
 			Message: "Failed to create post",
@@ -147,20 +251,43 @@ func StorePost(c *fiber.Ctx) error {
 
 // UpdatePost godoc
 // @Summary      Update post
-// @Description  Update post and return it
+// @Description  Update post and return it, published and scheduled posts require the publish permission
 // @Tags         Post
 // @Accept       json
 // @Headers      Content-Type application/json
 // @Param	 id path int true "Post ID"
 // @Produce      json
 // @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
 // @Success      200  {array}   models.Post
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
 // @Router       /posts/{id} [put]
 func UpdatePost(c *fiber.Ctx) error {
 
+	// find post by id, only the owner or a user allowed to manage any post can update it
+	post, status, message := findAuthorizedPost(c, policies.CanUpdatePost)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	// live posts went through review, only users allowed to publish them can change them
+	user, _ := middlewares.FindUserByToken(c)
+	if (post.Status == models.PostStatusPublished || post.Status == models.PostStatusScheduled) && !policies.CanPublishPost(user, post) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+			Status:  false,
+			Message: "Published and scheduled posts can only be edited by an editor, unpublish it first",
+			Data:    []interface{}{},
+		})
+	}
+
 	// get and parse request body
 	postRequest := new(models.UpdatePostRequest)
 	if err := c.BodyParser(postRequest); err != nil {
@@ -181,18 +308,50 @@ func UpdatePost(c *fiber.Ctx) error {
 		})
 	}
 
-	// find post by id
-	var post models.Post
-	if err := db.DB.First(&post, c.Params("id")).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
-			Status:  false,
-			Message: "Post not found",
-			Data:    []interface{}{},
-		})
+	updates := map[string]interface{}{}
+	if postRequest.Title != "" {
+		updates["title"] = postRequest.Title
+	}
+
+	if postRequest.Body != "" {
+		updates["body"] = postRequest.Body
+	}
+
+	if postRequest.CategoryID != nil {
+		if *postRequest.CategoryID == 0 {
+			updates["category_id"] = nil
+		} else if categoryExists(*postRequest.CategoryID) {
+			updates["category_id"] = *postRequest.CategoryID
+		} else {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+				Status:  false,
+				Message: "Category not found",
+				Data:    []interface{}{},
+			})
+		}
 	}
 
-	// update post
-	if err := db.DB.Model(&post).Updates(postRequest).Error; err != nil {
+	// update post, tags are replaced only when given
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&post).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if postRequest.Tags == nil {
+			return nil
+		}
+
+		tags, err := findOrCreateTags(tx, postRequest.Tags)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&post).Omit("Tags.*").Association("Tags").Replace(tags)
+	})
+
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
 			Message: "Failed to update post",
@@ -202,6 +361,8 @@ func UpdatePost(c *fiber.Ctx) error {
 		})
 	}
 
+	db.DB.Preload("Category").Preload("Tags").First(&post, post.ID)
+
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
 		Message: "Successfully updated post",
@@ -219,25 +380,40 @@ func UpdatePost(c *fiber.Ctx) error {
 // @Param	 id path int true "Post ID"
 // @Produce      json
 // @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
 // @Success      200  {array}   models.Post
 // @Failure      400  {object}  utils.Response
 // @Failure      404  {object}  utils.Response
 // @Failure      500  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
 // @Router       /posts/{id} [delete]
 func DestroyPost(c *fiber.Ctx) error {
 
-	// find post by id
-	var post models.Post
-	if err := db.DB.First(&post, c.Params("id")).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+	// find post by id, only the owner or a user allowed to manage any post can delete it
+	post, status, message := findAuthorizedPost(c, policies.CanDeletePost)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
 			Status:  false,
-			Message: "Post not found",
+			Message: message,
 			Data:    []interface{}{},
 		})
 	}
 
-	// delete post
-	if err := db.DB.Delete(&post).Error; err != nil {
+	// delete post with its comments, reactions and tag links
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Select("Tags").Delete(&post).Error
+	})
+
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
 			Status:  false,
 			Message: "Failed to delete post",
@@ -253,3 +429,52 @@ func DestroyPost(c *fiber.Ctx) error {
 		Data:    map[string]interface{}{},
 	})
 }
+
+// postsTagged keeps the posts with the tag
+func postsTagged(slug string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("posts.id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.slug = ?)", slug)
+	}
+}
+
+// postsInCategory keeps the posts in the category or any of its subcategories
+func postsInCategory(slug string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(`posts.category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE slug = ?
+				UNION
+				SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+			)
+			SELECT id FROM tree
+		)`, slug)
+	}
+}
+
+// findVisiblePost loads the post from the ":id" param when the user may see it, user may be nil
+func findVisiblePost(c *fiber.Ctx, user *models.User) (models.Post, error) {
+	var post models.Post
+	err := db.DB.Scopes(policies.VisiblePosts(user)).First(&post, c.Params("id")).Error
+
+	return post, err
+}
+
+// findAuthorizedPost loads the post from the ":id" param and checks the caller against
+// the policy, returning the status and message to respond with when it is not fiber.StatusOK
+func findAuthorizedPost(c *fiber.Ctx, can func(*models.User, models.Post) bool) (models.Post, int, string) {
+	var post models.Post
+	if err := db.DB.First(&post, c.Params("id")).Error; err != nil {
+		return post, fiber.StatusNotFound, "Post not found"
+	}
+
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return post, fiber.StatusUnauthorized, "Unauthorized"
+	}
+
+	if !can(user, post) {
+		return post, fiber.StatusForbidden, "You are not allowed to modify this post"
+	}
+
+	return post, fiber.StatusOK, ""
+}
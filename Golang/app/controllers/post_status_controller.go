@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/policies"
+	"boilerplate/app/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SubmitPost godoc
+// @Summary      Submit post for review
+// @Description  Move a draft to in review so an editor can publish it
+// @Tags         Post
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/submit [post]
+func SubmitPost(c *fiber.Ctx) error {
+	return transitionPost(c, policies.CanUpdatePost, models.PostStatusInReview, models.PostStatusDraft)
+}
+
+// PublishPost godoc
+// @Summary      Publish post
+// @Description  Make a post public, editors can publish any post and authors only their own when allowed to skip the review.
+// @Description  A post in review with a future publication date is scheduled for it.
+// @Tags         Post
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/publish [post]
+func PublishPost(c *fiber.Ctx) error {
+	return transitionPost(c, policies.CanPublishPost, models.PostStatusPublished, models.PostStatusDraft, models.PostStatusInReview, models.PostStatusScheduled, models.PostStatusArchived)
+}
+
+// UnpublishPost godoc
+// @Summary      Unpublish post
+// @Description  Move a post back to draft, hiding it from the public
+// @Tags         Post
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/unpublish [post]
+func UnpublishPost(c *fiber.Ctx) error {
+	return transitionPost(c, policies.CanUpdatePost, models.PostStatusDraft, models.PostStatusPublished, models.PostStatusInReview, models.PostStatusScheduled, models.PostStatusArchived)
+}
+
+// ArchivePost godoc
+// @Summary      Archive post
+// @Description  Archive a post, it is kept but no longer public
+// @Tags         Post
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/archive [post]
+func ArchivePost(c *fiber.Ctx) error {
+	return transitionPost(c, policies.CanUpdatePost, models.PostStatusArchived, models.PostStatusDraft, models.PostStatusInReview, models.PostStatusScheduled, models.PostStatusPublished)
+}
+
+// SchedulePost godoc
+// @Summary      Schedule post
+// @Description  Publish a post automatically at a future date, or move the date of a scheduled post.
+// @Description  Authors who can't publish on their own send the post for review with its date instead, publishing it then schedules it.
+// @Tags         Post
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Param        request body models.SchedulePostRequest true "Schedule post request"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/schedule [put]
+func SchedulePost(c *fiber.Ctx) error {
+	// find post by id, only the owner or a user allowed to manage any post can schedule it
+	post, status, message := findAuthorizedPost(c, policies.CanUpdatePost)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	// get and parse request body
+	scheduleRequest := new(models.SchedulePostRequest)
+	if err := c.BodyParser(scheduleRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// validate user input
+	validationErrors := utils.GlobalValidator.Validate(scheduleRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	if !scheduleRequest.PublishAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Publication date must be in the future",
+			Data:    []interface{}{},
+		})
+	}
+
+	if post.Status != models.PostStatusDraft && post.Status != models.PostStatusInReview && post.Status != models.PostStatusScheduled {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Post can't move from " + post.Status + " to " + models.PostStatusScheduled,
+			Data:    []interface{}{},
+		})
+	}
+
+	// schedule post, the scheduler publishes it once due. Without the right to publish
+	// the date waits in review until an editor approves it
+	user, _ := middlewares.FindUserByToken(c)
+
+	current := post.Status
+	post.Status = models.PostStatusScheduled
+	if !policies.CanPublishPost(user, post) {
+		post.Status = models.PostStatusInReview
+	}
+	post.PublishAt = &scheduleRequest.PublishAt
+	post.PublishedAt = nil
+
+	// only write over the status read above, the scheduler may have published the post since
+	res := db.DB.Model(&post).Where("status = ?", current).Updates(map[string]interface{}{
+		"status":       post.Status,
+		"publish_at":   scheduleRequest.PublishAt,
+		"published_at": nil,
+	})
+	if res.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to update post",
+			Data: map[string]interface{}{
+				"error": res.Error.Error(),
+			},
+		})
+	}
+
+	if res.RowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Post status changed in the meantime, reload it and try again",
+			Data:    []interface{}{},
+		})
+	}
+
+	message = "Successfully scheduled post"
+	if post.Status == models.PostStatusInReview {
+		message = "Successfully submitted post for review, it will be published on its date once approved"
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: message,
+		Data:    post,
+	})
+}
+
+// CancelSchedulePost godoc
+// @Summary      Cancel scheduled publication
+// @Description  Move a scheduled post back to draft
+// @Tags         Post
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  models.Post
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      409  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/schedule [delete]
+func CancelSchedulePost(c *fiber.Ctx) error {
+	return transitionPost(c, policies.CanUpdatePost, models.PostStatusDraft, models.PostStatusScheduled)
+}
+
+// transitionPost moves the post from one of the allowed statuses to the new one
+func transitionPost(c *fiber.Ctx, can func(*models.User, models.Post) bool, to string, from ...string) error {
+	// find post by id and check the caller against the policy
+	post, status, message := findAuthorizedPost(c, can)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || post.Status == s
+	}
+
+	if !allowed {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Post can't move from " + post.Status + " to " + to,
+			Data:    []interface{}{},
+		})
+	}
+
+	current := post.Status
+
+	// an approved post with a future date waits for it
+	if to == models.PostStatusPublished && post.Status == models.PostStatusInReview && post.PublishAt != nil && post.PublishAt.After(time.Now()) {
+		to = models.PostStatusScheduled
+	}
+
+	// publishing dates the post, going back to draft or review clears the date
+	// while archived posts keep theirs. Any other transition cancels a schedule.
+	updates := map[string]interface{}{"status": to}
+	post.Status = to
+	if to != models.PostStatusScheduled {
+		updates["publish_at"] = nil
+		post.PublishAt = nil
+	}
+
+	switch to {
+	case models.PostStatusPublished:
+		now := time.Now()
+		updates["published_at"] = now
+		post.PublishedAt = &now
+	case models.PostStatusDraft, models.PostStatusInReview:
+		updates["published_at"] = nil
+		post.PublishedAt = nil
+	}
+
+	// only write over the status read above, the scheduler may have published the post since
+	res := db.DB.Model(&post).Where("status = ?", current).Updates(updates)
+	if res.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to update post",
+			Data: map[string]interface{}{
+				"error": res.Error.Error(),
+			},
+		})
+	}
+
+	if res.RowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(utils.Response{
+			Status:  false,
+			Message: "Post status changed in the meantime, reload it and try again",
+			Data:    []interface{}{},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully updated post",
+		Data:    post,
+	})
+}
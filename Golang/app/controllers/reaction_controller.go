@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// StoreReaction godoc
+// @Summary      React to post
+// @Description  Leave a reaction of one of the REACTION_TYPES on a published post, reacting twice with the same type changes nothing
+// @Tags         Reaction
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Param	 type path string true "Reaction type"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/reactions/{type} [put]
+func StoreReaction(c *fiber.Ctx) error {
+	post, user, status, message := findReactablePost(c)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	// the unique index makes a repeated reaction a no-op
+	reaction := models.Reaction{PostID: post.ID, UserID: user.ID, Type: c.Params("type")}
+	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to save reaction",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return reactionsResponse(c, post, user)
+}
+
+// DestroyReaction godoc
+// @Summary      Remove reaction
+// @Description  Remove a reaction of the current user from a post, removing a missing reaction changes nothing
+// @Tags         Reaction
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Post ID"
+// @Param	 type path string true "Reaction type"
+// @Security	 ApiKeyAuth
+// @Security	 PersonalApiKey
+// @Success      200  {object}  utils.Response
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /posts/{id}/reactions/{type} [delete]
+func DestroyReaction(c *fiber.Ctx) error {
+	post, user, status, message := findReactablePost(c)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(utils.Response{
+			Status:  false,
+			Message: message,
+			Data:    []interface{}{},
+		})
+	}
+
+	err := db.DB.Where("post_id = ? AND user_id = ? AND type = ?", post.ID, user.ID, c.Params("type")).Delete(&models.Reaction{}).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to remove reaction",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return reactionsResponse(c, post, user)
+}
+
+// findReactablePost loads the published post from the ":id" param for the current user and
+// checks the ":type" param, returning the status and message to respond with when it is not fiber.StatusOK
+func findReactablePost(c *fiber.Ctx) (models.Post, *models.User, int, string) {
+	var post models.Post
+
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return post, nil, fiber.StatusUnauthorized, "Unauthorized"
+	}
+
+	if !reactionTypeAllowed(c.Params("type")) {
+		return post, user, fiber.StatusBadRequest, "Unknown reaction type, use one of " + config.REACTION_TYPES
+	}
+
+	if post, err = findVisiblePost(c, user); err != nil {
+		return post, user, fiber.StatusNotFound, "Post not found"
+	}
+
+	if post.Status != models.PostStatusPublished {
+		return post, user, fiber.StatusBadRequest, "Only published posts can get reactions"
+	}
+
+	return post, user, fiber.StatusOK, ""
+}
+
+func reactionsResponse(c *fiber.Ctx, post models.Post, user *models.User) error {
+	posts := []models.Post{post}
+	withReactions(posts, user)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully updated reactions",
+		Data: map[string]interface{}{
+			"reactions":    posts[0].Reactions,
+			"my_reactions": posts[0].MyReactions,
+		},
+	})
+}
+
+func reactionTypeAllowed(reactionType string) bool {
+	for _, allowed := range strings.Split(config.REACTION_TYPES, ",") {
+		if strings.TrimSpace(allowed) == reactionType && reactionType != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withReactions fills the reaction counts of each post and the reactions the viewer left,
+// one query for the counts and one for the viewer whatever the number of posts
+func withReactions(posts []models.Post, viewer *models.User) {
+	if len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(posts))
+	index := map[uint]int{}
+	for i := range posts {
+		ids[i] = posts[i].ID
+		index[posts[i].ID] = i
+		posts[i].Reactions = map[string]int64{}
+		posts[i].MyReactions = []string{}
+	}
+
+	var counts []struct {
+		PostID uint
+		Type   string
+		Count  int64
+	}
+	db.DB.Model(&models.Reaction{}).
+		Select("post_id, type, COUNT(*) AS count").
+		Where("post_id IN ?", ids).
+		Group("post_id, type").
+		Scan(&counts)
+
+	for _, count := range counts {
+		posts[index[count.PostID]].Reactions[count.Type] = count.Count
+	}
+
+	if viewer == nil {
+		return
+	}
+
+	var mine []models.Reaction
+	db.DB.Where("post_id IN ? AND user_id = ?", ids, viewer.ID).Find(&mine)
+
+	for _, reaction := range mine {
+		i := index[reaction.PostID]
+		posts[i].MyReactions = append(posts[i].MyReactions, reaction.Type)
+	}
+
+	for i := range posts {
+		sort.Strings(posts[i].MyReactions)
+	}
+}
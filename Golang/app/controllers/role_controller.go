@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexRole godoc
+// @Summary      Get all roles
+// @Description  Get all roles with their permissions
+// @Tags         Role
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.Role
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Router       /roles [get]
+func IndexRole(c *fiber.Ctx) error {
+	var roles []models.Role
+	db.DB.Preload("Permissions").Order("id asc").Find(&roles)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched roles",
+		Data:    roles,
+	})
+}
+
+// UpdateUserRoles godoc
+// @Summary      Update user roles
+// @Description  Replace the roles of a user
+// @Tags         Role
+// @Accept       json
+// @Headers      Content-Type application/json
+// @Param	 id path int true "User ID"
+// @Param        request body models.UpdateUserRolesRequest true "Update user roles request"
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  models.User
+// @Failure      400  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      403  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /admin/users/{id}/roles [put]
+func UpdateUserRoles(c *fiber.Ctx) error {
+	// get and parse request body
+	rolesRequest := new(models.UpdateUserRolesRequest)
+	if err := c.BodyParser(rolesRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Invalid request payload",
+			Data:    []interface{}{},
+		})
+	}
+
+	// validate user input
+	validationErrors := utils.GlobalValidator.Validate(rolesRequest)
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Validation errors",
+			Data:    validationErrors,
+		})
+	}
+
+	// find user by id
+	var user models.User
+	if err := db.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+			Status:  false,
+			Message: "User not found",
+			Data:    []interface{}{},
+		})
+	}
+
+	// every requested role must exist, a name may be repeated
+	names := slices.Clone(rolesRequest.Roles)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	var roles []models.Role
+	db.DB.Where("name IN ?", names).Find(&roles)
+	if len(roles) != len(names) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.Response{
+			Status:  false,
+			Message: "Unknown role",
+			Data:    []interface{}{},
+		})
+	}
+
+	// replace roles
+	if err := db.DB.Model(&user).Association("Roles").Replace(roles); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Failed to update user roles",
+			Data: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully updated user roles",
+		Data:    user,
+	})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"boilerplate/app/middlewares"
+	"boilerplate/app/utils"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexSession godoc
+// @Summary      Get sessions
+// @Description  Get the devices the current user is logged in on, the session of this request is marked as current
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Security	 ApiKeyAuth
+// @Success      200  {array}   models.Session
+// @Failure      401  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/sessions [get]
+func IndexSession(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	sessions, err := middlewares.ActiveSessions(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not fetch sessions",
+			Data:    nil,
+		})
+	}
+
+	current := middlewares.SessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched sessions",
+		Data:    sessions,
+	})
+}
+
+// DestroySession godoc
+// @Summary      Revoke session
+// @Description  Log out one device of the current user, its refresh and access tokens stop working
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Param	 id path int true "Session ID"
+// @Security	 ApiKeyAuth
+// @Success      200  {object}  utils.Response
+// @Failure      401  {object}  utils.Response
+// @Failure      404  {object}  utils.Response
+// @Failure      500  {object}  utils.Response
+// @Router       /me/sessions/{id} [delete]
+func DestroySession(c *fiber.Ctx) error {
+	// Get authenticated user from token
+	user, err := middlewares.FindUserByToken(c)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+			Status:  false,
+			Message: "Unauthorized",
+			Data:    nil,
+		})
+	}
+
+	// Revoke session, scoped to the current user
+	if err := middlewares.RevokeSession(user.ID, c.Params("id")); err != nil {
+		if errors.Is(err, middlewares.ErrSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(utils.Response{
+				Status:  false,
+				Message: "Session not found",
+				Data:    nil,
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.Response{
+			Status:  false,
+			Message: "Could not revoke session",
+			Data:    nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Session revoked successfully",
+		Data:    nil,
+	})
+}
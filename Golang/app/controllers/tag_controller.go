@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// IndexTag godoc
+// @Summary      Get tags
+// @Description  Get all tags with the number of published posts using them
+// @Tags         Tag
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.TagWithCount
+// @Router       /tags [get]
+func IndexTag(c *fiber.Ctx) error {
+	var tags []models.TagWithCount
+	db.DB.Model(&models.Tag{}).
+		Select("tags.*, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ? AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = posts.user_id AND users.deleted_at IS NOT NULL)", models.PostStatusPublished).
+		Group("tags.id").
+		Order("tags.name asc").
+		Find(&tags)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Response{
+		Status:  true,
+		Message: "Successfully fetched tags",
+		Data:    tags,
+	})
+}
+
+// findOrCreateTags returns the tags with the given names, creating the missing ones.
+// Names are matched by slug so "Go" and "go" are the same tag.
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		slug := utils.Slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		var tag models.Tag
+		if err := tx.Where(models.Tag{Slug: slug}).Attrs(models.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
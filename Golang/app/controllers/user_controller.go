@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"boilerplate/app/db"
+	"boilerplate/app/middlewares"
 	"boilerplate/app/models"
+	"boilerplate/app/policies"
 	"boilerplate/app/utils"
 	"github.com/gofiber/fiber/v2"
 	"strconv"
@@ -40,9 +42,13 @@ func ShowUser(c *fiber.Ctx) error {
 		})
 	}
 
-	// find posts by user id, and paginate
+	// find posts by user id the viewer may see, and paginate
+	viewer, _ := middlewares.FindUserByToken(c)
+
 	var posts []models.Post
-	db.DB.Where("user_id = ?", user.ID).Scopes(db.Paginate(page, perPage)).Order("id desc").Find(&posts)
+	db.DB.Where("user_id = ?", user.ID).Preload("Category").Preload("Tags").Scopes(policies.VisiblePosts(viewer), db.Paginate(page, perPage)).Order("id desc").Find(&posts)
+	withCommentCounts(posts)
+	withReactions(posts, viewer)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Response{
 		Status:  true,
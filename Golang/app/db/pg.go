@@ -3,9 +3,11 @@ package db
 import (
 	"boilerplate/app/config"
 	"boilerplate/app/models"
+	"boilerplate/app/utils"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"log"
 )
 
 var DB *gorm.DB
@@ -32,10 +34,106 @@ func Init() {
 }
 
 func Migrate() {
-	err := DB.AutoMigrate(&models.User{}, &models.Post{})
+	// accounts created before email verification existed are trusted, without this
+	// RequireVerifiedEmail would lock all of them out of write routes
+	backfillEmailVerified := DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	err := DB.AutoMigrate(&models.User{}, &models.Post{}, &models.Category{}, &models.Tag{}, &models.Comment{}, &models.Reaction{}, &models.RefreshToken{}, &models.Session{}, &models.OneTimeToken{}, &models.RecoveryCode{}, &models.Role{}, &models.Permission{}, &models.ApiKey{}, &models.UserIdentity{}, &models.LoginAttempt{}, &models.LoginThrottle{}, &models.AuditLog{})
 	if err != nil {
 		panic("Failed to migrate database")
 	}
+
+	if backfillEmailVerified {
+		// users has no created_at, the migration time is the closest we know
+		if err := DB.Exec("UPDATE users SET email_verified_at = NOW() WHERE email_verified_at IS NULL").Error; err != nil {
+			panic("Failed to migrate database")
+		}
+	}
+
+	if !DB.Migrator().HasIndex(&models.User{}, "idx_users_email_lower") {
+		if err := DB.Transaction(uniqueEmails); err != nil {
+			panic("Failed to migrate database")
+		}
+	}
+
+	// recovery codes used to be unique across all users, they only need to be per user
+	if DB.Migrator().HasIndex(&models.RecoveryCode{}, "idx_recovery_codes_code_hash") {
+		if err := DB.Migrator().DropIndex(&models.RecoveryCode{}, "idx_recovery_codes_code_hash"); err != nil {
+			panic("Failed to migrate database")
+		}
+	}
+}
+
+// uniqueEmails makes emails case insensitive and unique. Registering used to accept an
+// address twice, only the oldest account could log in with it, the later ones are kept
+// under a placeholder address so the index can be built.
+func uniqueEmails(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_users_email").Error; err != nil {
+		return err
+	}
+
+	result := tx.Exec(`UPDATE users SET email = 'duplicate-' || id || '-' || lower(trim(email))
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (PARTITION BY lower(trim(email)) ORDER BY id) AS position FROM users
+			) AS ranked WHERE position > 1
+		)`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("renamed %d accounts sharing their email with an older account to duplicate-<id>-<email>", result.RowsAffected)
+	}
+
+	err := tx.Exec("UPDATE users SET email = lower(trim(email)), pending_email = lower(trim(pending_email))").Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec("CREATE UNIQUE INDEX idx_users_email_lower ON users (lower(email))").Error
+}
+
+// Seed creates the default roles and permissions, gives users without a role the
+// author role and makes the ADMIN_EMAIL user an admin
+func Seed() {
+	for roleName, permissionNames := range models.DefaultRoles {
+		var role models.Role
+		if err := DB.Where(models.Role{Name: roleName}).FirstOrCreate(&role).Error; err != nil {
+			panic("Failed to seed roles")
+		}
+
+		permissions := make([]models.Permission, len(permissionNames))
+		for i, name := range permissionNames {
+			if err := DB.Where(models.Permission{Name: name}).FirstOrCreate(&permissions[i]).Error; err != nil {
+				panic("Failed to seed permissions")
+			}
+		}
+
+		if err := DB.Model(&role).Association("Permissions").Append(permissions); err != nil {
+			panic("Failed to seed role permissions")
+		}
+	}
+
+	err := DB.Exec(`INSERT INTO user_roles (user_id, role_id)
+		SELECT users.id, roles.id FROM users, roles
+		WHERE roles.name = ? AND NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)`,
+		models.RoleAuthor,
+	).Error
+	if err != nil {
+		panic("Failed to seed user roles")
+	}
+
+	if config.ADMIN_EMAIL != "" {
+		err := DB.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT users.id, roles.id FROM users, roles
+			WHERE lower(users.email) = ? AND roles.name = ? ON CONFLICT DO NOTHING`,
+			utils.NormalizeEmail(config.ADMIN_EMAIL), models.RoleAdmin,
+		).Error
+		if err != nil {
+			panic("Failed to seed admin user")
+		}
+	}
 }
 
 func Paginate(page int, perPage int) func(db *gorm.DB) *gorm.DB {
@@ -0,0 +1,68 @@
+package hasher
+
+import (
+	"boilerplate/app/config"
+	"log"
+)
+
+type Hasher interface {
+	// Hash returns the encoded hash of the password, salt and parameters included
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the encoded hash
+	Verify(encoded string, password string) (bool, error)
+	// Supports reports whether the encoded hash was produced by this algorithm
+	Supports(encoded string) bool
+	// NeedsRehash reports whether the encoded hash uses weaker or different parameters
+	NeedsRehash(encoded string) bool
+}
+
+// Default hashes new passwords, stored hashes of any supported algorithm still verify
+var Default = New(config.PASSWORD_HASH_ALGORITHM)
+
+var supported = []Hasher{
+	New("bcrypt"),
+	New("argon2id"),
+}
+
+func New(algorithm string) Hasher {
+	switch algorithm {
+	case "bcrypt":
+		return Bcrypt{Cost: config.BCRYPT_COST}
+	default:
+		return Argon2id{
+			Memory:      uint32(config.ARGON2_MEMORY),
+			Iterations:  uint32(config.ARGON2_ITERATIONS),
+			Parallelism: uint8(config.ARGON2_PARALLELISM),
+			SaltLength:  16,
+			KeyLength:   32,
+		}
+	}
+}
+
+// Hash hashes a password with the default hasher
+func Hash(password string) (string, error) {
+	return Default.Hash(password)
+}
+
+// Verify checks a password against a stored hash of any supported algorithm
+func Verify(encoded string, password string) bool {
+	for _, h := range supported {
+		if !h.Supports(encoded) {
+			continue
+		}
+
+		ok, err := h.Verify(encoded, password)
+		if err != nil {
+			log.Printf("verify password hash: %v", err)
+		}
+
+		return ok
+	}
+
+	return false
+}
+
+// NeedsRehash reports whether a stored hash should be replaced by one from the default hasher
+func NeedsRehash(encoded string) bool {
+	return !Default.Supports(encoded) || Default.NeedsRehash(encoded)
+}
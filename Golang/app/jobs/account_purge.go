@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PurgeDeletedAccounts permanently removes the accounts deleted longer than
+// ACCOUNT_DELETION_GRACE ago, together with their posts and credentials.
+// Each account is claimed with SKIP LOCKED so replicas never purge the same one.
+func PurgeDeletedAccounts() error {
+	for {
+		purged := false
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Unscoped().Model(&models.User{}).
+				Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-config.ACCOUNT_DELETION_GRACE)).
+				Limit(1).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				return nil
+			}
+
+			purged = true
+
+			return purgeAccount(tx, ids[0])
+		})
+
+		if err != nil || !purged {
+			return err
+		}
+	}
+}
+
+func purgeAccount(tx *gorm.DB, userID uint) error {
+	if err := tx.Exec("DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?)", userID).Error; err != nil {
+		return err
+	}
+
+	// comments with replies are emptied and left without author like DestroyComment
+	// does, so other users' replies keep their thread
+	if err := tx.Model(&models.Comment{}).
+		Where("user_id = ? AND EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)", userID).
+		Updates(map[string]interface{}{"body": "", "removed_at": time.Now(), "user_id": nil}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM reactions WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?)", userID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?)", userID).Error; err != nil {
+		return err
+	}
+
+	for _, model := range []interface{}{
+		&models.Comment{},
+		&models.Reaction{},
+		&models.Post{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.OneTimeToken{},
+		&models.RecoveryCode{},
+		&models.ApiKey{},
+		&models.UserIdentity{},
+		&models.LoginAttempt{},
+	} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(&models.User{}, userID).Error
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"boilerplate/app/config"
+	"log"
+	"time"
+)
+
+// Start runs the background jobs for as long as the process lives
+func Start() {
+	go every("purge deleted accounts", config.ACCOUNT_PURGE_INTERVAL, PurgeDeletedAccounts)
+	go every("publish scheduled posts", config.POST_SCHEDULER_INTERVAL, PublishScheduledPosts)
+}
+
+func every(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+
+		<-ticker.C
+	}
+}
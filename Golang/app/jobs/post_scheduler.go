@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const scheduledPostBatch = 100
+
+// PublishScheduledPosts publishes the scheduled posts that are due. Rows are locked
+// with SKIP LOCKED so replicas running the same job never publish a post twice.
+func PublishScheduledPosts() error {
+	for {
+		published := 0
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Model(&models.Post{}).
+				Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+				Order("publish_at asc").
+				Limit(scheduledPostBatch).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				return nil
+			}
+
+			published = len(ids)
+
+			// the post is dated with the time it was scheduled for, not when the job ran
+			return tx.Model(&models.Post{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"status":       models.PostStatusPublished,
+				"published_at": gorm.Expr("publish_at"),
+				"publish_at":   nil,
+			}).Error
+		})
+
+		if err != nil || published < scheduledPostBatch {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"boilerplate/app/config"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application, replace it to plug in a real provider
+var Default = New(config.MAIL_DRIVER)
+
+func New(driver string) Mailer {
+	switch driver {
+	case "file":
+		return &FileMailer{Path: config.MAIL_FILE_PATH}
+	default:
+		return LogMailer{}
+	}
+}
+
+// Send delivers a message from the configured sender through the default mailer
+func Send(to string, subject string, body string) error {
+	return Default.Send(Message{
+		From:    config.MAIL_FROM,
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+// LogMailer writes messages to the application log, for local development
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("[mail] from=%s to=%s subject=%q\n%s\n", msg.From, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer appends messages to a file, for local development
+type FileMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(m.Path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), msg.From, msg.To, msg.Subject, msg.Body)
+
+	return err
+}
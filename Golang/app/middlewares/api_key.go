@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const apiKeyPrefix = "sk_"
+
+var ErrInvalidApiKey = errors.New("invalid or revoked API key")
+
+const ApiKeyHeader = "X-API-Key"
+
+// Authenticate accepts a personal API key from "X-API-Key" or "Authorization: ApiKey ..."
+// and falls back to EnableJWT for bearer tokens
+func Authenticate() fiber.Handler {
+	jwtHandler := EnableJWT()
+
+	return func(c *fiber.Ctx) error {
+		raw := apiKeyFromRequest(c)
+		if raw == "" {
+			return jwtHandler(c)
+		}
+
+		user, key, err := userFromApiKey(raw)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+				Status:  false,
+				Message: "Invalid or revoked API key",
+				Data:    nil,
+			})
+		}
+
+		c.Locals("authUser", user)
+		c.Locals("apiKey", key)
+
+		return c.Next()
+	}
+}
+
+// GenerateApiKey returns a new raw key and its visible prefix, only the hash of the key is stored
+func GenerateApiKey() (string, string, error) {
+	id, err := utils.RandomToken(6)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := apiKeyPrefix + id
+
+	return prefix + "." + secret, prefix, nil
+}
+
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := c.Get(ApiKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, key, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if found && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(key)
+	}
+
+	return ""
+}
+
+func userFromApiKey(raw string) (*models.User, *models.ApiKey, error) {
+	prefix, _, found := strings.Cut(raw, ".")
+	if !found {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	var key models.ApiKey
+	if err := db.DB.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(raw)), []byte(key.KeyHash)) != 1 {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	var user models.User
+	if err := db.DB.Preload("Roles.Permissions").Where("id = ?", key.UserID).First(&user).Error; err != nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	// A key only carries the permissions it was scoped to
+	for i := range user.Roles {
+		scoped := user.Roles[i].Permissions[:0]
+		for _, permission := range user.Roles[i].Permissions {
+			if key.HasScope(permission.Name) {
+				scoped = append(scoped, permission)
+			}
+		}
+		user.Roles[i].Permissions = scoped
+	}
+
+	now := time.Now()
+	key.LastUsedAt = &now
+	db.DB.Model(&key).UpdateColumn("last_used_at", now)
+
+	return &user, &key, nil
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecordAudit stores an audit log entry for the request
+func RecordAudit(c *fiber.Ctx, action string, actorID uint, subjectID *uint, details map[string]interface{}) error {
+	return db.DB.Create(&models.AuditLog{
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Action:    action,
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Details:   details,
+	}).Error
+}
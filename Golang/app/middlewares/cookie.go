@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"boilerplate/app/config"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SecureCookie builds a cookie with the attributes from config (Secure, SameSite, Domain)
+func SecureCookie(name string, value string, expires time.Time, httpOnly bool) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   config.COOKIE_DOMAIN,
+		Expires:  expires,
+		Secure:   config.COOKIE_SECURE,
+		HTTPOnly: httpOnly,
+		SameSite: config.COOKIE_SAMESITE,
+	}
+}
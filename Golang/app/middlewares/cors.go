@@ -8,7 +8,8 @@ import (
 
 func Cors() fiber.Handler {
 	return cors.New(cors.Config{
-		AllowOrigins: config.CORS_ALLOWED_ORIGINS,
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:     config.CORS_ALLOWED_ORIGINS,
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, " + ApiKeyHeader + ", " + CsrfHeader,
+		AllowCredentials: config.CORS_ALLOW_CREDENTIALS,
 	})
 }
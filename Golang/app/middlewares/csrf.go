@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"boilerplate/app/utils"
+	"crypto/subtle"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	CsrfCookie = "csrfToken"
+	CsrfHeader = "X-CSRF-Token"
+)
+
+// IssueCsrfToken sets a new double-submit token in a cookie readable by the frontend,
+// which must echo it in the X-CSRF-Token header of cookie authenticated requests
+func IssueCsrfToken(c *fiber.Ctx, expires time.Time) error {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(SecureCookie(CsrfCookie, token, expires, false))
+
+	return nil
+}
+
+// ClearCsrfToken removes the double-submit cookie
+func ClearCsrfToken(c *fiber.Ctx) {
+	c.Cookie(SecureCookie(CsrfCookie, "", time.Now().Add(-time.Hour), false))
+}
+
+// ValidCsrfToken reports whether the X-CSRF-Token header matches the csrfToken cookie
+func ValidCsrfToken(c *fiber.Ctx) bool {
+	cookie := c.Cookies(CsrfCookie)
+	header := c.Get(CsrfHeader)
+
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DenyImpersonation refuses the request when an admin is impersonating the user,
+// for account changes only the real user may make. It must be registered after
+// EnableJWT or Authenticate.
+func DenyImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if user, ok := c.Locals("authUser").(*models.User); ok && user.Impersonator != nil {
+			return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+				Status:  false,
+				Message: "This action is not allowed while impersonating a user",
+				Data:    nil,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IntrospectToken describes an access or refresh token issued by this API (RFC 7662),
+// the hint only decides which kind is tried first
+func IntrospectToken(raw string, hint string) models.IntrospectionResponse {
+	lookups := []func(string) (models.IntrospectionResponse, bool){introspectAccessToken, introspectRefreshToken}
+	if hint == models.TokenTypeRefresh {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if response, ok := lookup(raw); ok {
+			return response
+		}
+	}
+
+	return models.IntrospectionResponse{Active: false}
+}
+
+// RevokeToken revokes the session of an access or refresh token (RFC 7009). Unknown
+// or already invalid tokens are not an error, access tokens without a session
+// (impersonation) expire on their own.
+func RevokeToken(raw string, hint string) error {
+	if hint != models.TokenTypeAccess {
+		err := RevokeRefreshToken(raw)
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			return err
+		}
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := ParseJWT(raw, claims); err != nil {
+		return nil
+	}
+
+	user, err := userFromClaims(claims)
+	if err != nil {
+		return nil
+	}
+
+	if sid, ok := claims["sid"].(float64); ok {
+		err := RevokeSession(user.ID, strconv.Itoa(int(sid)))
+		if !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func introspectAccessToken(raw string) (models.IntrospectionResponse, bool) {
+	claims := jwt.MapClaims{}
+	if _, err := ParseJWT(raw, claims); err != nil {
+		return models.IntrospectionResponse{}, false
+	}
+
+	user, err := userFromClaims(claims)
+	if err != nil {
+		return models.IntrospectionResponse{}, false
+	}
+
+	exp, _ := claims["exp"].(float64)
+	iat, _ := claims["iat"].(float64)
+
+	response := models.IntrospectionResponse{
+		Active:    true,
+		TokenType: models.TokenTypeAccess,
+		Sub:       strconv.Itoa(int(user.ID)),
+		Username:  user.Email,
+		Scope:     strings.Join(user.PermissionNames(), " "),
+		Exp:       int64(exp),
+		Iat:       int64(iat),
+	}
+
+	if user.Impersonator != nil {
+		response.Act = &models.IntrospectionActor{Sub: strconv.Itoa(int(user.Impersonator.ID))}
+	}
+
+	return response, true
+}
+
+func introspectRefreshToken(raw string) (models.IntrospectionResponse, bool) {
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(raw)).First(&stored).Error; err != nil {
+		return models.IntrospectionResponse{}, false
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.IntrospectionResponse{}, false
+	}
+
+	var user models.User
+	if err := db.DB.Preload("Roles.Permissions").Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+		return models.IntrospectionResponse{}, false
+	}
+
+	return models.IntrospectionResponse{
+		Active:    true,
+		TokenType: models.TokenTypeRefresh,
+		Sub:       strconv.Itoa(int(user.ID)),
+		Username:  user.Email,
+		Scope:     strings.Join(user.PermissionNames(), " "),
+		Exp:       stored.ExpiresAt.Unix(),
+		Iat:       stored.CreatedAt.Unix(),
+	}, true
+}
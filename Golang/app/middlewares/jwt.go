@@ -1,68 +1,130 @@
 package middlewares
 
 import (
-	"boilerplate/app/config"
 	"boilerplate/app/db"
 	"boilerplate/app/models"
 	"boilerplate/app/utils"
+	"errors"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 	"strings"
 	"time"
 )
 
 func EnableJWT() fiber.Handler {
 	return jwtware.New(jwtware.Config{
-		SigningKey:   jwtware.SigningKey{Key: []byte(config.JWT_SECRET)},
-		ErrorHandler: JwtError,
+		KeyFunc:        keyFunc,
+		ErrorHandler:   JwtError,
+		SuccessHandler: jwtSuccess,
 	})
 }
 
-func GenerateJWT(user models.User) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
+// GenerateJWT starts a session for the device and returns "access|refresh" tokens
+func GenerateJWT(user models.User, userAgent string, ip string) (string, error) {
+	session, r, err := StartSession(user, userAgent, ip)
+	if err != nil {
+		return "Error generating refresh token : ", err
+	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	refreshClaims := refreshToken.Claims.(jwt.MapClaims)
+	t, err := GenerateAccessJWT(user, session.ID)
+	if err != nil {
+		return "Error generating access token : ", err
+	}
+
+	return t + "|" + r, nil
+}
+
+func GenerateAccessJWT(user models.User, sessionID uint) (string, error) {
+	// Load roles so they can be embedded as claims
+	if user.Roles == nil {
+		if err := db.DB.Model(&user).Association("Roles").Find(&user.Roles); err != nil {
+			return "", err
+		}
+	}
+
+	claims := jwt.MapClaims{}
 
 	claims["user_id"] = user.ID
 	claims["email"] = user.Email
+	claims["roles"] = user.RoleNames()
+	claims["ver"] = user.TokenVersion
+	claims["sid"] = sessionID
+	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
 
-	refreshClaims["user_id"] = user.ID
-	refreshClaims["exp"] = time.Now().Add(time.Hour * 24 * 30).Unix()
+	return signJWT(claims)
+}
 
-	t, err := token.SignedString([]byte(config.JWT_SECRET))
-	if err != nil {
-		return "Error generating access token : ", err
+// GenerateImpersonationJWT signs a short-lived access token for the user with the
+// impersonating admin in the "act" claim, it has no session and can't be refreshed
+func GenerateImpersonationJWT(user models.User, impersonator models.User, ttl time.Duration) (string, error) {
+	if user.Roles == nil {
+		if err := db.DB.Model(&user).Association("Roles").Find(&user.Roles); err != nil {
+			return "", err
+		}
 	}
 
-	r, err := refreshToken.SignedString([]byte(config.JWT_SECRET))
-	if err != nil {
-		return "Error generating refresh token : ", err
+	claims := jwt.MapClaims{}
+
+	claims["user_id"] = user.ID
+	claims["email"] = user.Email
+	claims["roles"] = user.RoleNames()
+	claims["ver"] = user.TokenVersion
+	claims["act"] = map[string]interface{}{
+		"user_id": impersonator.ID,
+		"ver":     impersonator.TokenVersion,
 	}
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(ttl).Unix()
 
-	return t + "|" + r, nil
+	return signJWT(claims)
 }
 
-func GenerateRefreshJWT(user models.User) (string, error) {
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
+// GeneratePurposeJWT signs a short-lived token that can only be used for the given purpose,
+// such as an email verification link, and never as an access token
+func GeneratePurposeJWT(userID uint, purpose string, ttl time.Duration, extra jwt.MapClaims) (string, error) {
+	claims := jwt.MapClaims{}
+	for key, value := range extra {
+		claims[key] = value
+	}
 
-	refreshClaims := refreshToken.Claims.(jwt.MapClaims)
+	claims["user_id"] = userID
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
 
-	refreshClaims["user_id"] = user.ID
-	refreshClaims["exp"] = time.Now().Add(time.Hour * 24 * 30).Unix()
+	return signJWT(claims)
+}
+
+// ParsePurposeJWT validates a token generated by GeneratePurposeJWT for the same purpose
+func ParsePurposeJWT(t string, purpose string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := ParseJWT(t, claims)
 
-	r, err := refreshToken.SignedString([]byte(config.JWT_SECRET))
 	if err != nil {
-		return "Error generating refresh token : ", err
+		return nil, err
+	}
+
+	if claims["purpose"] != purpose {
+		return nil, errors.New("invalid token purpose")
 	}
 
-	return r, nil
+	return claims, nil
 }
 
 func FindUserByToken(c *fiber.Ctx) (*models.User, error) {
+	// User already resolved by EnableJWT or Authenticate
+	if user, ok := c.Locals("authUser").(*models.User); ok {
+		return user, nil
+	}
+
+	// Personal API key
+	if raw := apiKeyFromRequest(c); raw != "" {
+		user, _, err := userFromApiKey(raw)
+		return user, err
+	}
+
 	// Get header "Authorization"
 	auth := c.Request().Header.Peek("Authorization")
 
@@ -82,25 +144,14 @@ func FindUserByToken(c *fiber.Ctx) (*models.User, error) {
 
 	// Parse token
 	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(t, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWT_SECRET), nil
-	})
+	_, err := ParseJWT(t, claims)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Get user id
-	userId := int(claims["user_id"].(float64))
-
-	// Check if user exists
-	var user models.User
-	if err := db.DB.Where("id = ?", userId).First(&user).Error; err != nil {
-		return nil, err
-	}
-
-	// Return user
-	return &user, nil
+	// Get user and check the token has not been revoked
+	return userFromClaims(claims)
 }
 
 func JwtError(c *fiber.Ctx, err error) error {
@@ -119,3 +170,102 @@ func JwtError(c *fiber.Ctx, err error) error {
 			Data:    nil,
 		})
 }
+
+// RevokeAllSessions logs the user out everywhere by revoking every refresh
+// token and bumping the token version embedded in access tokens
+func RevokeAllSessions(userID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func jwtSuccess(c *fiber.Ctx) error {
+	token := c.Locals("user").(*jwt.Token)
+
+	user, err := userFromClaims(token.Claims.(jwt.MapClaims))
+	if err != nil {
+		return JwtError(c, err)
+	}
+
+	c.Locals("authUser", user)
+
+	if sid, ok := token.Claims.(jwt.MapClaims)["sid"].(float64); ok {
+		c.Locals("sessionID", uint(sid))
+	}
+
+	return c.Next()
+}
+
+func userFromClaims(claims jwt.MapClaims) (*models.User, error) {
+	// Purpose tokens (email links, etc.) are not access tokens
+	if _, ok := claims["purpose"]; ok {
+		return nil, errors.New("invalid token purpose")
+	}
+
+	// Get user id and token version
+	userId, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	version, _ := claims["ver"].(float64)
+
+	// Check if user exists, with roles and permissions for authorization
+	var user models.User
+	if err := db.DB.Preload("Roles.Permissions").Where("id = ?", int(userId)).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before the last "log out everywhere" are rejected
+	if uint(version) != user.TokenVersion {
+		return nil, errors.New("token has been revoked")
+	}
+
+	// Impersonation tokens stay valid only while the admin may still impersonate
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		impersonator, err := impersonatorFromClaims(act)
+		if err != nil {
+			return nil, err
+		}
+
+		user.Impersonator = impersonator
+	}
+
+	// Tokens of a logged out session are rejected too
+	if sid, ok := claims["sid"].(float64); ok && sessionRevoked(user.ID, uint(sid)) {
+		return nil, errors.New("session has been revoked")
+	}
+
+	return &user, nil
+}
+
+func impersonatorFromClaims(act map[string]interface{}) (*models.User, error) {
+	userId, ok := act["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	version, _ := act["ver"].(float64)
+
+	var impersonator models.User
+	if err := db.DB.Preload("Roles.Permissions").Where("id = ?", int(userId)).First(&impersonator).Error; err != nil {
+		return nil, err
+	}
+
+	if uint(version) != impersonator.TokenVersion || !impersonator.HasPermission(models.PermissionUsersImpersonate) {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return &impersonator, nil
+}
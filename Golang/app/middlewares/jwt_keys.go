@@ -0,0 +1,215 @@
+package middlewares
+
+import (
+	"boilerplate/app/config"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+var (
+	signingKeys = map[string]*signingKey{}
+	activeKey   *signingKey
+)
+
+// LoadKeys reads the PEM keys listed in JWT_KEYS ("kid=path,kid=path"). Private keys can
+// sign and verify, public keys only verify so retired keys keep validating old tokens.
+// Without JWT_KEYS tokens are signed with JWT_SECRET using HS256, one of them must be set.
+func LoadKeys() {
+	if strings.TrimSpace(config.JWT_KEYS) == "" {
+		// an empty HMAC key would let anyone sign valid tokens
+		if config.JWT_SECRET == "" {
+			panic("Failed to load JWT keys: set JWT_KEYS or JWT_SECRET")
+		}
+
+		activeKey = &signingKey{method: jwt.SigningMethodHS256, privateKey: []byte(config.JWT_SECRET), publicKey: []byte(config.JWT_SECRET)}
+		return
+	}
+
+	for _, entry := range strings.Split(config.JWT_KEYS, ",") {
+		kid, path, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || kid == "" || path == "" {
+			panic("Failed to load JWT keys: invalid JWT_KEYS entry " + entry)
+		}
+
+		key, err := loadKey(kid, path)
+		if err != nil {
+			panic("Failed to load JWT keys: " + err.Error())
+		}
+
+		signingKeys[kid] = key
+	}
+
+	key, ok := signingKeys[config.JWT_ACTIVE_KID]
+	if !ok || key.privateKey == nil {
+		panic("Failed to load JWT keys: JWT_ACTIVE_KID must name a private key from JWT_KEYS")
+	}
+
+	activeKey = key
+}
+
+// JWKS returns the public keys that verify tokens issued by this service
+func JWKS() []JWK {
+	keys := make([]JWK, 0, len(signingKeys))
+	for _, key := range signingKeys {
+		if jwk, ok := key.jwk(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+
+	return keys
+}
+
+// ParseJWT verifies a token against the key set and fills the claims
+func ParseJWT(t string, claims jwt.MapClaims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(t, claims, keyFunc)
+}
+
+func signJWT(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(activeKey.method, claims)
+	if activeKey.id != "" {
+		token.Header["kid"] = activeKey.id
+	}
+
+	return token.SignedString(activeKey.privateKey)
+}
+
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	key := activeKey
+	if len(signingKeys) > 0 {
+		kid, _ := token.Header["kid"].(string)
+		if key = signingKeys[kid]; key == nil {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+
+	// Never let the token pick the algorithm, it must match the key
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.publicKey, nil
+}
+
+func loadKey(kid string, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from configuration
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	key := &signingKey{id: kid}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key.privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key.publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if signer, ok := key.privateKey.(crypto.Signer); ok {
+		key.publicKey = signer.Public()
+	}
+
+	if key.method, err = methodForKey(key.publicKey); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+func methodForKey(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, errors.New("unsupported key type")
+}
+
+func (k *signingKey) jwk() (JWK, bool) {
+	jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+
+	switch pub := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return jwk, false
+	}
+
+	return jwk, true
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// LoginRetryAfter returns how long the email or IP address is locked out, zero when a login may be attempted
+func LoginRetryAfter(email string, ip string) time.Duration {
+	var throttles []models.LoginThrottle
+	db.DB.Where("key IN ? AND locked_until > ?", []string{emailThrottleKey(email), ipThrottleKey(ip)}, time.Now()).Find(&throttles)
+
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if until := time.Until(*throttle.LockedUntil); until > wait {
+			wait = until
+		}
+	}
+
+	return wait
+}
+
+// RecordLoginFailure audits a failed login and backs off exponentially once the email or the
+// IP address reaches its limit. It reports whether this failure locked the email.
+func RecordLoginFailure(email string, ip string, userID *uint, reason string) bool {
+	recordLoginAttempt(email, ip, userID, false, reason)
+
+	emailLocked := bumpThrottle(emailThrottleKey(email), config.LOGIN_MAX_ATTEMPTS)
+	bumpThrottle(ipThrottleKey(ip), config.LOGIN_IP_MAX_ATTEMPTS)
+
+	return emailLocked
+}
+
+// RecordLoginSuccess audits a successful login and resets the failures of the email
+func RecordLoginSuccess(email string, ip string, userID uint) {
+	recordLoginAttempt(email, ip, &userID, true, "")
+	UnlockLogin(email)
+}
+
+// UnlockLogin clears the failures and lockout of an email
+func UnlockLogin(email string) {
+	db.DB.Where("key = ?", emailThrottleKey(email)).Delete(&models.LoginThrottle{})
+}
+
+func recordLoginAttempt(email string, ip string, userID *uint, success bool, reason string) {
+	db.DB.Create(&models.LoginAttempt{
+		Email:   strings.ToLower(email),
+		IP:      ip,
+		UserID:  userID,
+		Success: success,
+		Reason:  reason,
+	})
+}
+
+// bumpThrottle counts a failure in a single upsert so concurrent failures are all counted
+func bumpThrottle(key string, maxAttempts int) bool {
+	now := time.Now()
+
+	// Old failures are forgotten after the window
+	var failures int
+	err := db.DB.Raw(`INSERT INTO login_throttles (key, failures, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failures`,
+		key, now, now.Add(-config.LOGIN_ATTEMPT_WINDOW),
+	).Scan(&failures).Error
+	if err != nil {
+		log.Printf("login throttle %s: %v", key, err)
+		return false
+	}
+
+	if failures < maxAttempts {
+		return false
+	}
+
+	lockout := config.LOGIN_LOCKOUT_BASE << min(failures-maxAttempts, 20)
+	if lockout <= 0 || lockout > config.LOGIN_LOCKOUT_MAX {
+		lockout = config.LOGIN_LOCKOUT_MAX
+	}
+
+	if err := db.DB.Model(&models.LoginThrottle{}).Where("key = ?", key).Update("locked_until", now.Add(lockout)).Error; err != nil {
+		log.Printf("login throttle %s: %v", key, err)
+	}
+
+	return failures == maxAttempts
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/models"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var oauthClients = parseOAuthClients(config.OAUTH_CLIENTS)
+
+// RequireOAuthClient authenticates a service with its client credentials from OAUTH_CLIENTS,
+// sent with HTTP Basic (client_secret_basic) or as form fields (client_secret_post)
+func RequireOAuthClient() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, secret := clientCredentials(c)
+
+		expected, ok := oauthClients[id]
+		if !ok || id == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+
+			return c.Status(fiber.StatusUnauthorized).JSON(models.OAuthError{
+				Error:            "invalid_client",
+				ErrorDescription: "Client authentication failed",
+			})
+		}
+
+		c.Locals("oauthClient", id)
+
+		return c.Next()
+	}
+}
+
+func clientCredentials(c *fiber.Ctx) (string, string) {
+	scheme, encoded, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", ""
+	}
+
+	// Credentials are form-encoded before being joined (RFC 6749 section 2.3.1)
+	id, secret, _ := strings.Cut(string(decoded), ":")
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+
+	return id, secret
+}
+
+func parseOAuthClients(value string) map[string]string {
+	clients := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		id, secret, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if found && id != "" && secret != "" {
+			clients[id] = secret
+		}
+	}
+
+	return clients
+}
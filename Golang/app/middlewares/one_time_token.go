@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"errors"
+	"time"
+)
+
+var ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+
+// IssueOneTimeToken creates a single-use token for the given purpose.
+// Previously issued tokens with the same purpose are invalidated.
+func IssueOneTimeToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := invalidateOneTimeTokens(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token := models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := db.DB.Create(&token).Error; err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// ConsumeOneTimeToken marks the token as used and returns its owner
+func ConsumeOneTimeToken(raw string, purpose string) (*models.User, error) {
+	var token models.OneTimeToken
+	if err := db.DB.Where("token_hash = ? AND purpose = ?", utils.HashToken(raw), purpose).First(&token).Error; err != nil {
+		return nil, ErrInvalidOneTimeToken
+	}
+
+	// Conditional update so a token can't be redeemed twice concurrently
+	res := db.DB.Model(&models.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, time.Now()).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidOneTimeToken
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", token.UserID).First(&user).Error; err != nil {
+		return nil, ErrInvalidOneTimeToken
+	}
+
+	return &user, nil
+}
+
+// FindOneTimeToken returns the owner of a valid token without redeeming it
+func FindOneTimeToken(raw string, purpose string) (*models.User, error) {
+	var token models.OneTimeToken
+	if err := db.DB.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(raw), purpose, time.Now()).
+		First(&token).Error; err != nil {
+		return nil, ErrInvalidOneTimeToken
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", token.UserID).First(&user).Error; err != nil {
+		return nil, ErrInvalidOneTimeToken
+	}
+
+	return &user, nil
+}
+
+func invalidateOneTimeTokens(userID uint, purpose string) error {
+	return db.DB.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
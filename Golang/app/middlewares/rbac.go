@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"boilerplate/app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Require allows the request when the authenticated user has at least one of the
+// permissions through its roles, it must be registered after EnableJWT or Authenticate
+func Require(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := FindUserByToken(c)
+		if err != nil || user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+				Status:  false,
+				Message: "Unauthorized",
+				Data:    nil,
+			})
+		}
+
+		for _, permission := range permissions {
+			if user.HasPermission(permission) {
+				return c.Next()
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+			Status:  false,
+			Message: "Forbidden",
+			Data:    nil,
+		})
+	}
+}
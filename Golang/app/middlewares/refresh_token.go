@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"boilerplate/app/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const refreshTokenTTL = time.Hour * 24 * 30
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same family and
+// marks its session as seen. Presenting a token that was already rotated or revoked
+// revokes the whole family.
+func RotateRefreshToken(raw string, userAgent string, ip string) (*models.User, *models.Session, string, error) {
+	if raw == "" {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(raw)).First(&stored).Error; err != nil {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil {
+		_ = RevokeRefreshTokenFamily(stored.FamilyID)
+		return nil, nil, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	var newRaw string
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// A revoked session can't be refreshed anymore
+		s, err := sessionForFamily(tx, user.ID, stored.FamilyID, userAgent, ip)
+		if err != nil {
+			return err
+		}
+		if s.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+		session = s
+
+		// Only one request may rotate a given token, a concurrent loser is treated as reuse
+		res := tx.Model(&stored).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		r, next, err := createRefreshToken(tx, user.ID, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+		newRaw = r
+
+		if err := tx.Model(&stored).Update("replaced_by_id", next.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(session).Update("last_seen_at", time.Now()).Error
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrInvalidRefreshToken) {
+		_ = RevokeRefreshTokenFamily(stored.FamilyID)
+		return nil, nil, "", err
+	}
+
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return &user, session, newRaw, nil
+}
+
+// RevokeRefreshToken revokes the login the given refresh token belongs to
+func RevokeRefreshToken(raw string) error {
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(raw)).First(&stored).Error; err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	return RevokeRefreshTokenFamily(stored.FamilyID)
+}
+
+// RevokeRefreshTokenFamily revokes every active token issued from the same login and its session
+func RevokeRefreshTokenFamily(familyID string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func createRefreshToken(tx *gorm.DB, userID uint, familyID string) (string, models.RefreshToken, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+
+	if familyID == "" {
+		familyID = uuid.NewString()
+	}
+
+	token := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+
+	if err := tx.Create(&token).Error; err != nil {
+		return "", models.RefreshToken{}, err
+	}
+
+	return raw, token, nil
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"boilerplate/app/db"
+	"boilerplate/app/models"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// StartSession records a new login from the given device and issues the first
+// refresh token of its family
+func StartSession(user models.User, userAgent string, ip string) (*models.Session, string, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		FamilyID:   uuid.NewString(),
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: now,
+	}
+
+	var raw string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		r, _, err := createRefreshToken(tx, user.ID, session.FamilyID)
+		raw = r
+
+		return err
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &session, raw, nil
+}
+
+// ActiveSessions returns the sessions of the user that can still be refreshed
+func ActiveSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := db.DB.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, time.Now().Add(-refreshTokenTTL)).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+// RevokeSession logs out a single session of the user
+func RevokeSession(userID uint, sessionID string) error {
+	var session models.Session
+	if err := db.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+
+	return RevokeRefreshTokenFamily(session.FamilyID)
+}
+
+// SessionID returns the session of the access token used for the request, 0 for API keys
+func SessionID(c *fiber.Ctx) uint {
+	id, _ := c.Locals("sessionID").(uint)
+	return id
+}
+
+// sessionForFamily returns the session of a refresh token family, creating it
+// for families issued before sessions were tracked
+func sessionForFamily(tx *gorm.DB, userID uint, familyID string, userAgent string, ip string) (*models.Session, error) {
+	session := models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: time.Now(),
+	}
+
+	err := tx.Where(models.Session{FamilyID: familyID}).FirstOrCreate(&session).Error
+
+	return &session, err
+}
+
+func sessionRevoked(userID uint, sessionID uint) bool {
+	var count int64
+	db.DB.Model(&models.Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).Count(&count)
+
+	return count == 0
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email address,
+// it must be registered after EnableJWT and is a no-op when verification is disabled
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !config.EMAIL_VERIFICATION_REQUIRED {
+			return c.Next()
+		}
+
+		user, err := FindUserByToken(c)
+		if err != nil || user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.Response{
+				Status:  false,
+				Message: "Unauthorized",
+				Data:    nil,
+			})
+		}
+
+		if user.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(utils.Response{
+				Status:  false,
+				Message: "Email address is not verified",
+				Data:    nil,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type ApiKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"uniqueIndex"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateApiKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=3,max=50"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// HasScope reports whether the key was granted the permission
+func (k ApiKey) HasScope(permission string) bool {
+	for _, scope := range k.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	AuditImpersonationStarted = "impersonation.started"
+)
+
+// AuditLog records a sensitive action, ActorID did it and SubjectID was affected
+type AuditLog struct {
+	ID        uint                   `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	ActorID   uint                   `json:"actor_id" gorm:"index"`
+	SubjectID *uint                  `json:"subject_id" gorm:"index"`
+	Action    string                 `json:"action" gorm:"index"`
+	IP        string                 `json:"ip"`
+	UserAgent string                 `json:"user_agent"`
+	Details   map[string]interface{} `json:"details" gorm:"serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Category nests under ParentID, a post in a subcategory also belongs to its ancestors
+type Category struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug" gorm:"uniqueIndex"`
+	ParentID  *uint      `json:"parent_id" gorm:"index"`
+	Children  []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	CreatedAt time.Time  `json:"-"`
+	PostCount int64      `json:"post_count" gorm:"-"`
+}
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name" validate:"required,min=2,max=50"`
+	ParentID *uint  `json:"parent_id"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Comment on a post, replies point to their parent and to the top level comment
+// of their thread (RootID) so a page of threads loads in one query. UserID is
+// nil once the author's account has been purged.
+type Comment struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	PostID    uint       `json:"post_id" gorm:"index"`
+	UserID    *uint      `json:"user_id" gorm:"index"`
+	User      *User      `json:"user,omitempty"`
+	ParentID  *uint      `json:"parent_id" gorm:"index"`
+	RootID    *uint      `json:"-" gorm:"index"`
+	Depth     int        `json:"depth"`
+	Body      string     `json:"body" gorm:"type:text"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Replies   []Comment  `json:"replies" gorm:"-"`
+}
+
+type CreateCommentRequest struct {
+	Body     string `json:"body" validate:"required,min=1,max=5000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required,min=1,max=5000"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// LoginAttempt is the audit trail of every password login
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Email     string    `json:"email" gorm:"index"`
+	IP        string    `json:"ip" gorm:"index"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginThrottle counts recent failures for an email or an IP address
+type LoginThrottle struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Key          string     `json:"key" gorm:"uniqueIndex"`
+	Failures     int        `json:"failures"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+}
+
+type UnlockLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	UserID    uint       `json:"user_id" gorm:"uniqueIndex:idx_recovery_codes_user_code"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex:idx_recovery_codes_user_code"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MfaEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type MfaCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MfaDisableRequest struct {
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmation_token"`
+	Code              string `json:"code" validate:"required"`
+}
+
+type MfaLoginRequest struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MfaRequiredResponse struct {
+	MfaRequired bool   `json:"mfa_required"`
+	MfaToken    string `json:"mfa_token"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
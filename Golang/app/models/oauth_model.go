@@ -0,0 +1,30 @@
+package models
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// IntrospectionResponse is the token state returned by /oauth/introspect (RFC 7662),
+// only Active is set for an invalid, expired or revoked token
+type IntrospectionResponse struct {
+	Active    bool                `json:"active"`
+	TokenType string              `json:"token_type,omitempty"`
+	Sub       string              `json:"sub,omitempty"`
+	Username  string              `json:"username,omitempty"`
+	Scope     string              `json:"scope,omitempty"`
+	Exp       int64               `json:"exp,omitempty"`
+	Iat       int64               `json:"iat,omitempty"`
+	Act       *IntrospectionActor `json:"act,omitempty"`
+}
+
+// IntrospectionActor is the admin acting on behalf of the subject of an impersonation token
+type IntrospectionActor struct {
+	Sub string `json:"sub"`
+}
+
+// OAuthError is the error body of the OAuth endpoints (RFC 6749 section 5.2)
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
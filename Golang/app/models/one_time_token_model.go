@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeMagicLink     = "magic_link"
+	TokenPurposeConfirmation  = "confirmation"
+)
+
+// OneTimeToken is a hashed, expiring token that can be redeemed only once
+type OneTimeToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Purpose   string     `json:"purpose" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -1,19 +1,48 @@
 package models
 
+import "time"
+
+const (
+	PostStatusDraft     = "draft"
+	PostStatusInReview  = "in_review"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
 type Post struct {
-	ID     uint   `json:"id" gorm:"primaryKey;autoIncrement:true"`
-	Title  string `json:"title"`
-	Body   string `json:"body" gorm:"type:text"`
-	UserID uint   `json:"user_id"`
-	User   *User  `json:"user,omitempty"`
+	ID           uint             `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Title        string           `json:"title"`
+	Body         string           `json:"body" gorm:"type:text"`
+	Status       string           `json:"status" gorm:"not null;default:published;index"`
+	PublishAt    *time.Time       `json:"publish_at" gorm:"index"`
+	PublishedAt  *time.Time       `json:"published_at"`
+	UserID       uint             `json:"user_id"`
+	User         *User            `json:"user,omitempty"`
+	CategoryID   *uint            `json:"category_id" gorm:"index"`
+	Category     *Category        `json:"category,omitempty"`
+	Tags         []Tag            `json:"tags" gorm:"many2many:post_tags"`
+	CommentCount int64            `json:"comment_count" gorm:"-"`
+	Reactions    map[string]int64 `json:"reactions" gorm:"-"`
+	MyReactions  []string         `json:"my_reactions" gorm:"-"`
 }
 
 type CreatePostRequest struct {
-	Title string `json:"title" validate:"required,min=8,max=30"`
-	Body  string `json:"body" validate:"required,min=8"`
+	Title      string   `json:"title" validate:"required,min=8,max=30"`
+	Body       string   `json:"body" validate:"required,min=8"`
+	Tags       []string `json:"tags" validate:"max=10,dive,min=2,max=30"`
+	CategoryID *uint    `json:"category_id"`
 }
 
+// UpdatePostRequest leaves omitted fields untouched, tags replace the current ones
+// and a category_id of 0 removes the category
 type UpdatePostRequest struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
+	Title      string   `json:"title"`
+	Body       string   `json:"body"`
+	Tags       []string `json:"tags" validate:"omitempty,max=10,dive,min=2,max=30"`
+	CategoryID *uint    `json:"category_id"`
+}
+
+type SchedulePostRequest struct {
+	PublishAt time.Time `json:"publish_at" validate:"required"`
 }
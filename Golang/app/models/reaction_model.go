@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Reaction of a user to a post, a user can leave each type of reaction once
+type Reaction struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	PostID    uint      `json:"post_id" gorm:"uniqueIndex:idx_reactions_post_user_type"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_reactions_post_user_type;index"`
+	Type      string    `json:"type" gorm:"uniqueIndex:idx_reactions_post_user_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	UserID       uint       `json:"user_id" gorm:"index"`
+	FamilyID     string     `json:"family_id" gorm:"index"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
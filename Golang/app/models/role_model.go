@@ -0,0 +1,120 @@
+package models
+
+import "sort"
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+)
+
+const (
+	PermissionPostsCreate      = "posts.create"
+	PermissionPostsUpdateOwn   = "posts.update.own"
+	PermissionPostsUpdateAny   = "posts.update.any"
+	PermissionPostsDeleteOwn   = "posts.delete.own"
+	PermissionPostsDeleteAny   = "posts.delete.any"
+	PermissionPostsPublishOwn  = "posts.publish.own"
+	PermissionPostsPublishAny  = "posts.publish.any"
+	PermissionCommentsCreate   = "comments.create"
+	PermissionReactionsCreate  = "reactions.create"
+	PermissionCategoriesManage = "categories.manage"
+	PermissionUsersManage      = "users.manage"
+	PermissionUsersImpersonate = "users.impersonate"
+)
+
+// DefaultRoles are created by db.Seed, permissions added later in the database are kept.
+// Authors can't publish on their own, they submit their posts for review by an editor.
+var DefaultRoles = map[string][]string{
+	RoleAdmin: {
+		PermissionPostsCreate,
+		PermissionPostsUpdateOwn,
+		PermissionPostsUpdateAny,
+		PermissionPostsDeleteOwn,
+		PermissionPostsDeleteAny,
+		PermissionPostsPublishOwn,
+		PermissionPostsPublishAny,
+		PermissionCommentsCreate,
+		PermissionReactionsCreate,
+		PermissionCategoriesManage,
+		PermissionUsersManage,
+		PermissionUsersImpersonate,
+	},
+	RoleEditor: {
+		PermissionPostsCreate,
+		PermissionPostsUpdateOwn,
+		PermissionPostsUpdateAny,
+		PermissionPostsDeleteOwn,
+		PermissionPostsDeleteAny,
+		PermissionPostsPublishOwn,
+		PermissionPostsPublishAny,
+		PermissionCommentsCreate,
+		PermissionReactionsCreate,
+		PermissionCategoriesManage,
+	},
+	RoleAuthor: {
+		PermissionPostsCreate,
+		PermissionPostsUpdateOwn,
+		PermissionPostsDeleteOwn,
+		PermissionCommentsCreate,
+		PermissionReactionsCreate,
+	},
+}
+
+type Role struct {
+	ID          uint         `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Name        string       `json:"name" gorm:"uniqueIndex"`
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions"`
+}
+
+type Permission struct {
+	ID   uint   `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Name string `json:"name" gorm:"uniqueIndex"`
+}
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1"`
+}
+
+// RoleNames returns the names of the loaded roles
+func (u User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+
+	return names
+}
+
+// PermissionNames returns the sorted names of the permissions granted by the loaded roles,
+// Roles.Permissions must be preloaded
+func (u User) PermissionNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, role := range u.Roles {
+		for _, p := range role.Permissions {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// HasPermission reports whether any loaded role grants the permission,
+// Roles.Permissions must be preloaded
+func (u User) HasPermission(permission string) bool {
+	for _, role := range u.Roles {
+		for _, p := range role.Permissions {
+			if p.Name == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Session is a login on a device, it lives as long as its refresh token family
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Current    bool       `json:"current" gorm:"-"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// TagWithCount is a tag with the number of published posts using it
+type TagWithCount struct {
+	Tag
+	PostCount int64 `json:"post_count"`
+}
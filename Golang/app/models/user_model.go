@@ -1,10 +1,38 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
-	ID           uint   `json:"id" gorm:"primaryKey;autoIncrement:true"`
-	Name         string `json:"name"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"-"`
+	ID                 uint           `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Name               string         `json:"name"`
+	Email              string         `json:"email"`
+	EmailVerifiedAt    *time.Time     `json:"email_verified_at"`
+	PendingEmail       string         `json:"-"`
+	PasswordHash       string         `json:"-"`
+	TokenVersion       uint           `json:"-" gorm:"not null;default:0"`
+	TOTPSecret         string         `json:"-"`
+	TOTPLastStep       int64          `json:"-" gorm:"not null;default:0"`
+	TwoFactorEnabledAt *time.Time     `json:"-"`
+	Roles              []Role         `json:"roles,omitempty" gorm:"many2many:user_roles"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+	Impersonator       *User          `json:"impersonator,omitempty" gorm:"-"`
+}
+
+// Profile is the current user as returned to themselves, with the account
+// details that the public user (GET /users/:id) leaves out
+type Profile struct {
+	User
+	PendingEmail       string     `json:"pending_email,omitempty"`
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at"`
+}
+
+// Profile returns the user with its private account details
+func (u User) Profile() Profile {
+	return Profile{User: u, PendingEmail: u.PendingEmail, TwoFactorEnabledAt: u.TwoFactorEnabledAt}
 }
 
 type LoginRequest struct {
@@ -20,13 +48,59 @@ type LoginResponse struct {
 type RegisterRequest struct {
 	Name         string `json:"name" validate:"required,min=3,max=20"`
 	Email        string `json:"email" validate:"required,email"`
-	PasswordHash string `json:"password" validate:"required,min=6,max=30"`
+	PasswordHash string `json:"password" validate:"required"`
 }
 
 type UpdateUserRequest struct {
 	Name string `json:"name" validate:"required,min=3,max=20"`
 }
 
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Sensitive changes are confirmed with the password, or with a confirmation token
+// sent by POST /me/confirmation when the account has no password
+
+type ChangePasswordRequest struct {
+	CurrentPassword   string `json:"current_password"`
+	ConfirmationToken string `json:"confirmation_token"`
+	Password          string `json:"password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email             string `json:"email" validate:"required,email"`
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+type DeleteAccountRequest struct {
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type RefreshResponse struct {
+	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,120 @@
+package oidc
+
+import (
+	"boilerplate/app/config"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Enabled reports whether a provider is configured
+func Enabled() bool {
+	return config.OIDC_CLIENT_ID != "" && config.OIDC_AUTH_URL != "" && config.OIDC_TOKEN_URL != "" && config.OIDC_USERINFO_URL != ""
+}
+
+// AuthCodeURL builds the authorization request URL with a PKCE S256 challenge of the verifier
+func AuthCodeURL(state string, verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.OIDC_CLIENT_ID)
+	query.Set("redirect_uri", config.OIDC_REDIRECT_URL)
+	query.Set("scope", config.OIDC_SCOPES)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(config.OIDC_AUTH_URL, "?") {
+		separator = "&"
+	}
+
+	return config.OIDC_AUTH_URL + separator + query.Encode()
+}
+
+// Exchange trades the authorization code and PKCE verifier for tokens
+func Exchange(code string, verifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", config.OIDC_REDIRECT_URL)
+	form.Set("client_id", config.OIDC_CLIENT_ID)
+	form.Set("code_verifier", verifier)
+	if config.OIDC_CLIENT_SECRET != "" {
+		form.Set("client_secret", config.OIDC_CLIENT_SECRET)
+	}
+
+	res, err := httpClient.PostForm(config.OIDC_TOKEN_URL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d", res.StatusCode)
+	}
+
+	token := new(Token)
+	if err := json.NewDecoder(res.Body).Decode(token); err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.New("token endpoint returned no access token")
+	}
+
+	return token, nil
+}
+
+// FetchUserInfo reads the claims of the authenticated user from the userinfo endpoint
+func FetchUserInfo(token *Token) (*UserInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, config.OIDC_USERINFO_URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo endpoint returned %d", res.StatusCode)
+	}
+
+	info := new(UserInfo)
+	if err := json.NewDecoder(res.Body).Decode(info); err != nil {
+		return nil, err
+	}
+
+	if info.Subject == "" {
+		return nil, errors.New("userinfo returned no subject")
+	}
+
+	return info, nil
+}
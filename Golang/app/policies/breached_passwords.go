@@ -0,0 +1,94 @@
+package policies
+
+import (
+	"boilerplate/app/config"
+	"bufio"
+	"crypto/sha1" // #nosec G505 -- breached password lists are published as SHA-1
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	breachedOnce   sync.Once
+	breachedHashes map[string]struct{}
+)
+
+// PasswordBreached reports whether the password is in the breached password list at
+// BREACHED_PASSWORDS_PATH. The path is either a file of SHA-1 hashes ("HASH" or
+// "HASH:COUNT" per line) loaded in memory, or a directory of k-anonymity range files
+// named after the first 5 hash characters and listing the remaining "SUFFIX:COUNT",
+// as served by the Have I Been Pwned range API. Without a path nothing is breached.
+func PasswordBreached(password string) bool {
+	if config.BREACHED_PASSWORDS_PATH == "" {
+		return false
+	}
+
+	sum := sha1.Sum([]byte(password)) // #nosec G401
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	info, err := os.Stat(config.BREACHED_PASSWORDS_PATH)
+	if err != nil {
+		log.Printf("breached passwords: %v", err)
+		return false
+	}
+
+	if info.IsDir() {
+		return breachedInRange(hash)
+	}
+
+	breachedOnce.Do(loadBreachedHashes)
+	_, found := breachedHashes[hash]
+
+	return found
+}
+
+func loadBreachedHashes() {
+	breachedHashes = map[string]struct{}{}
+
+	file, err := os.Open(config.BREACHED_PASSWORDS_PATH)
+	if err != nil {
+		log.Printf("breached passwords: %v", err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) == 40 {
+			breachedHashes[strings.ToUpper(hash)] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Printf("breached passwords: %v", err)
+	}
+}
+
+func breachedInRange(hash string) bool {
+	prefix, suffix := hash[:5], hash[5:]
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		file, err := os.Open(filepath.Join(config.BREACHED_PASSWORDS_PATH, name))
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+			if strings.EqualFold(line, suffix) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return false
+}
@@ -0,0 +1,38 @@
+package policies
+
+import (
+	"boilerplate/app/models"
+
+	"gorm.io/gorm"
+)
+
+// CanUpdateComment reports whether the user may edit or delete the comment, only its author can
+func CanUpdateComment(user *models.User, comment models.Comment) bool {
+	return user != nil && comment.UserID != nil && *comment.UserID == user.ID && comment.RemovedAt == nil
+}
+
+// CanModerateComments reports whether the user may hide comments on the post,
+// the author of the post and users allowed to edit any post can
+func CanModerateComments(user *models.User, post models.Post) bool {
+	if user == nil {
+		return false
+	}
+
+	return post.UserID == user.ID || user.HasPermission(models.PermissionPostsUpdateAny)
+}
+
+// VisibleComments limits a comment query on the post to the comments the user may read,
+// hidden comments are left to moderators and their own author
+func VisibleComments(user *models.User, post models.Post) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if CanModerateComments(user, post) {
+			return db
+		}
+
+		if user == nil {
+			return db.Where("comments.hidden_at IS NULL")
+		}
+
+		return db.Where("comments.hidden_at IS NULL OR comments.user_id = ?", user.ID)
+	}
+}
@@ -0,0 +1,188 @@
+package policies
+
+import (
+	"boilerplate/app/config"
+	"boilerplate/app/utils"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswordWords are guessed first by attackers, a password built on them is weak
+var commonPasswordWords = []string{
+	"password", "qwerty", "azerty", "qwertz", "asdf", "zxcv", "letmein", "welcome",
+	"admin", "login", "iloveyou", "monkey", "dragon", "master", "shadow", "sunshine",
+	"princess", "football", "baseball", "superman", "batman", "starwars", "trustno",
+	"secret", "hello", "freedom", "whatever", "summer", "winter", "spring", "autumn",
+}
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "@", "a", "5", "s", "$", "s", "7", "t")
+
+// CheckPassword validates a new password against the password policy. The email and
+// name of the user may not appear in it.
+func CheckPassword(password string, email string, name string) []utils.ErrorResponse {
+	var errs []utils.ErrorResponse
+	fail := func(message string) {
+		errs = append(errs, utils.ErrorResponse{Error: true, FieldName: "Password", Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < config.PASSWORD_MIN_LENGTH {
+		fail(fmt.Sprintf("Password must be at least %d characters", config.PASSWORD_MIN_LENGTH))
+	}
+
+	if length > config.PASSWORD_MAX_LENGTH {
+		fail(fmt.Sprintf("Password must be at most %d characters", config.PASSWORD_MAX_LENGTH))
+	}
+
+	if passwordClasses(password) < config.PASSWORD_MIN_CLASSES {
+		fail(fmt.Sprintf("Password must mix at least %d of lowercase, uppercase, digits and symbols", config.PASSWORD_MIN_CLASSES))
+	}
+
+	if containsPersonalInfo(password, email, name) {
+		fail("Password must not contain your email or name")
+	}
+
+	if PasswordScore(password) < config.PASSWORD_MIN_SCORE {
+		fail("Password is too easy to guess")
+	}
+
+	if PasswordBreached(password) {
+		fail("Password has appeared in a data breach, please choose another one")
+	}
+
+	return errs
+}
+
+// PasswordScore rates a password from 0 (too guessable) to 4 (very unguessable),
+// common words, repeats and sequences count as a single guess
+func PasswordScore(password string) int {
+	bits := passwordEntropy(password)
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 50:
+		return 2
+	case bits < 64:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func passwordEntropy(password string) float64 {
+	charset := 0
+	for _, class := range passwordClassSizes(password) {
+		charset += class
+	}
+	if charset == 0 {
+		return 0
+	}
+
+	bitsPerChar := math.Log2(float64(charset))
+
+	// Words are matched with leetspeak undone ("p@ssw0rd"), runs on the characters as typed
+	chars := []rune(strings.ToLower(password))
+	words := []rune(leetReplacer.Replace(string(chars)))
+
+	bits := 0.0
+	for i := 0; i < len(chars); {
+		if n := commonWordAt(words, i); n > 0 {
+			bits += math.Log2(float64(len(commonPasswordWords)))
+			i += n
+			continue
+		}
+
+		if n := runAt(chars, i); n >= 3 {
+			bits += bitsPerChar + math.Log2(float64(n))
+			i += n
+			continue
+		}
+
+		bits += bitsPerChar
+		i++
+	}
+
+	return bits
+}
+
+// commonWordAt returns the length of the longest common word starting at i
+func commonWordAt(chars []rune, i int) int {
+	rest := string(chars[i:])
+	longest := 0
+	for _, word := range commonPasswordWords {
+		if strings.HasPrefix(rest, word) && len(word) > longest {
+			longest = len(word)
+		}
+	}
+
+	return longest
+}
+
+// runAt returns the length of the repeated ("aaa") or sequential ("abc", "321") run starting at i
+func runAt(chars []rune, i int) int {
+	if i+1 >= len(chars) {
+		return 1
+	}
+
+	step := chars[i+1] - chars[i]
+	if step < -1 || step > 1 {
+		return 1
+	}
+
+	n := 2
+	for i+n < len(chars) && chars[i+n]-chars[i+n-1] == step {
+		n++
+	}
+
+	return n
+}
+
+func passwordClasses(password string) int {
+	classes := 0
+	for _, size := range passwordClassSizes(password) {
+		if size > 0 {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+// passwordClassSizes returns the alphabet size of each character class used
+func passwordClassSizes(password string) [4]int {
+	var sizes [4]int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			sizes[0] = 26
+		case unicode.IsUpper(r):
+			sizes[1] = 26
+		case unicode.IsDigit(r):
+			sizes[2] = 10
+		default:
+			sizes[3] = 33
+		}
+	}
+
+	return sizes
+}
+
+func containsPersonalInfo(password string, email string, name string) bool {
+	password = strings.ToLower(password)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+
+	parts := append([]string{local}, strings.Fields(strings.ToLower(name))...)
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package policies
+
+import (
+	"boilerplate/app/models"
+
+	"gorm.io/gorm"
+)
+
+// CanUpdatePost reports whether the user may modify the post, authors only their own
+func CanUpdatePost(user *models.User, post models.Post) bool {
+	if user == nil {
+		return false
+	}
+
+	if user.HasPermission(models.PermissionPostsUpdateAny) {
+		return true
+	}
+
+	return post.UserID == user.ID && user.HasPermission(models.PermissionPostsUpdateOwn)
+}
+
+// CanDeletePost reports whether the user may delete the post, authors only their own
+func CanDeletePost(user *models.User, post models.Post) bool {
+	if user == nil {
+		return false
+	}
+
+	if user.HasPermission(models.PermissionPostsDeleteAny) {
+		return true
+	}
+
+	return post.UserID == user.ID && user.HasPermission(models.PermissionPostsDeleteOwn)
+}
+
+// VisiblePosts limits a post query to the posts the user may read, user may be nil.
+// Unpublished posts are only visible to their author and to users allowed to edit any post,
+// posts of deleted accounts are hidden from everyone while they wait for the purge.
+func VisiblePosts(user *models.User) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = posts.user_id AND users.deleted_at IS NOT NULL)")
+
+		if user == nil {
+			return db.Where("posts.status = ?", models.PostStatusPublished)
+		}
+
+		if user.HasPermission(models.PermissionPostsUpdateAny) {
+			return db
+		}
+
+		return db.Where("(posts.status = ? OR posts.user_id = ?)", models.PostStatusPublished, user.ID)
+	}
+}
+
+// CanPublishPost reports whether the user may publish the post, authors only their own
+// when they have been allowed to skip the review
+func CanPublishPost(user *models.User, post models.Post) bool {
+	if user == nil {
+		return false
+	}
+
+	if user.HasPermission(models.PermissionPostsPublishAny) {
+		return true
+	}
+
+	return post.UserID == user.ID && user.HasPermission(models.PermissionPostsPublishOwn)
+}
@@ -0,0 +1,71 @@
+package policies
+
+import (
+	"boilerplate/app/models"
+	"testing"
+)
+
+const (
+	ownerID  = 1
+	otherID  = 2
+	editorID = 3
+)
+
+func TestPostOwnership(t *testing.T) {
+	post := models.Post{ID: 1, UserID: ownerID, Status: models.PostStatusPublished}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		update bool
+		delete bool
+	}{
+		{"other author", userWithRole(otherID, models.RoleAuthor), false, false},
+		{"owner", userWithRole(ownerID, models.RoleAuthor), true, true},
+		{"editor", userWithRole(editorID, models.RoleEditor), true, true},
+		{"guest", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanUpdatePost(tt.user, post); got != tt.update {
+				t.Errorf("CanUpdatePost = %v, want %v", got, tt.update)
+			}
+
+			if got := CanDeletePost(tt.user, post); got != tt.delete {
+				t.Errorf("CanDeletePost = %v, want %v", got, tt.delete)
+			}
+		})
+	}
+}
+
+func TestCanPublishPost(t *testing.T) {
+	post := models.Post{ID: 1, UserID: ownerID, Status: models.PostStatusDraft}
+
+	tests := []struct {
+		name string
+		user *models.User
+		want bool
+	}{
+		{"author without review rights", userWithRole(ownerID, models.RoleAuthor), false},
+		{"editor", userWithRole(editorID, models.RoleEditor), true},
+		{"guest", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanPublishPost(tt.user, post); got != tt.want {
+				t.Errorf("CanPublishPost = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func userWithRole(id uint, roleName string) *models.User {
+	role := models.Role{Name: roleName}
+	for _, name := range models.DefaultRoles[roleName] {
+		role.Permissions = append(role.Permissions, models.Permission{Name: name})
+	}
+
+	return &models.User{ID: id, Roles: []models.Role{role}}
+}
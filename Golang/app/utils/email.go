@@ -0,0 +1,8 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail trims and lowercases an email address, users are looked up by the normalized form
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -1,16 +1,57 @@
 package utils
 
 import (
+	"errors"
 	"github.com/joho/godotenv"
+	"io/fs"
 	"os"
+	"strconv"
+	"time"
 )
 
+// env is the result of loading .env, a missing file is fine when the
+// variables come from the environment (containers, tests)
 var env = godotenv.Load()
 
 func LoadEnv(key string) string {
-	if env != nil {
+	if env != nil && !errors.Is(env, fs.ErrNotExist) {
 		panic("Failed to load .env file")
 	}
 
 	return os.Getenv(key)
 }
+
+func LoadEnvDefault(key string, fallback string) string {
+	if value := LoadEnv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func LoadEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(LoadEnv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func LoadEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(LoadEnv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func LoadEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(LoadEnv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"boilerplate/app/config"
 	pg "boilerplate/app/db"
+	"boilerplate/app/jobs"
 	"boilerplate/app/middlewares"
 	"boilerplate/routes"
 
@@ -30,6 +31,10 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey PersonalApiKey
+// @in header
+// @name X-API-Key
 func main() {
 
 	// Init database
@@ -38,6 +43,15 @@ func main() {
 	// Migrate database
 	pg.Migrate()
 
+	// Seed roles and permissions
+	pg.Seed()
+
+	// Load JWT signing keys
+	middlewares.LoadKeys()
+
+	// Start background jobs
+	jobs.Start()
+
 	// Create new Fiber instance
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -62,6 +76,9 @@ func main() {
 		return c.SendString("Hello Fiber 👋!")
 	})
 
+	// Create routes for "/.well-known"
+	routes.WellKnownRoute(app)
+
 	// Create route for "/api"
 	api := app.Group("/api")
 
@@ -70,8 +87,22 @@ func main() {
 
 	// Create routes for "/api/v1"
 	routes.AuthRoute(v1)
+	routes.AccountRoute(v1)
+	routes.SessionRoute(v1)
+	routes.PasswordRoute(v1)
+	routes.EmailRoute(v1)
+	routes.MfaRoute(v1)
+	routes.OidcRoute(v1)
+	routes.OAuthRoute(v1)
 	routes.UserRoute(v1)
 	routes.PostRoute(v1)
+	routes.TagRoute(v1)
+	routes.CategoryRoute(v1)
+	routes.CommentRoute(v1)
+	routes.ReactionRoute(v1)
+	routes.RoleRoute(v1)
+	routes.AdminRoute(v1)
+	routes.ApiKeyRoute(v1)
 
 	// Custom 404 Handler
 	app.Use(func(c *fiber.Ctx) error {
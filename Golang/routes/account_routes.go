@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AccountRoute(app fiber.Router) {
+	app.Post("/me/confirmation", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.RequestConfirmation)
+	app.Delete("/me", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.DestroyAccount)
+	app.Get("/me/export", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.ExportAccount)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AdminRoute(app fiber.Router) {
+	app.Post("/admin/users/:id/impersonate", middlewares.EnableJWT(), middlewares.DenyImpersonation(), middlewares.Require(models.PermissionUsersImpersonate), controllers.Impersonate)
+	app.Get("/admin/audit-logs", middlewares.EnableJWT(), middlewares.Require(models.PermissionUsersManage), controllers.IndexAuditLog)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ApiKeyRoute(app fiber.Router) {
+	app.Get("/me/api-keys", middlewares.EnableJWT(), controllers.IndexApiKey)
+	app.Post("/me/api-keys", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.StoreApiKey)
+	app.Delete("/me/api-keys/:id", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.DestroyApiKey)
+}
@@ -9,8 +9,13 @@ import (
 
 func AuthRoute(app fiber.Router) {
 	app.Post("/login", controllers.Login)
+	app.Post("/login/unlock", controllers.UnlockLogin)
+	app.Post("/login/magic/request", controllers.RequestMagicLink)
+	app.Post("/login/magic", controllers.LoginMagicLink)
 	app.Post("/register", controllers.Register)
 	app.Post("/refresh", controllers.RefreshToken)
+	app.Post("/logout", controllers.Logout)
+	app.Post("/logout-all", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.LogoutAll)
 	app.Get("/me", middlewares.EnableJWT(), controllers.Profile)
 	app.Put("/me", middlewares.EnableJWT(), controllers.UpdateProfile)
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CategoryRoute(app fiber.Router) {
+	app.Get("/categories", controllers.IndexCategory)
+	app.Post("/categories", middlewares.Authenticate(), middlewares.Require(models.PermissionCategoriesManage), controllers.StoreCategory)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CommentRoute(app fiber.Router) {
+	app.Get("/posts/:id/comments", controllers.IndexComment)
+	app.Post("/posts/:id/comments", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionCommentsCreate), controllers.StoreComment)
+	app.Put("/comments/:id", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionCommentsCreate), controllers.UpdateComment)
+	app.Delete("/comments/:id", middlewares.Authenticate(), middlewares.Require(models.PermissionCommentsCreate), controllers.DestroyComment)
+	app.Post("/comments/:id/hide", middlewares.Authenticate(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.HideComment)
+	app.Post("/comments/:id/unhide", middlewares.Authenticate(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.UnhideComment)
+}
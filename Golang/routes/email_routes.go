@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func EmailRoute(app fiber.Router) {
+	app.Post("/email/verify", controllers.VerifyEmail)
+	app.Post("/email/resend", middlewares.EnableJWT(), controllers.ResendVerificationEmail)
+	app.Put("/me/email", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.ChangeEmail)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func MfaRoute(app fiber.Router) {
+	app.Post("/login/mfa", controllers.LoginMfa)
+	app.Post("/me/mfa/enroll", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.EnrollMfa)
+	app.Post("/me/mfa/confirm", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.ConfirmMfa)
+	app.Post("/me/mfa/disable", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.DisableMfa)
+	app.Post("/me/mfa/recovery-codes", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.RegenerateRecoveryCodes)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func OAuthRoute(app fiber.Router) {
+	app.Post("/oauth/introspect", middlewares.RequireOAuthClient(), controllers.Introspect)
+	app.Post("/oauth/revoke", middlewares.RequireOAuthClient(), controllers.Revoke)
+}
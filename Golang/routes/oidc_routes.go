@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func OidcRoute(app fiber.Router) {
+	app.Get("/auth/oidc/login", controllers.OidcLogin)
+	app.Get("/auth/oidc/callback", controllers.OidcCallback)
+	app.Get("/me/identities", middlewares.EnableJWT(), controllers.IndexIdentity)
+	app.Delete("/me/identities/:id", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.DestroyIdentity)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PasswordRoute(app fiber.Router) {
+	app.Post("/password/forgot", controllers.ForgotPassword)
+	app.Post("/password/reset", controllers.ResetPassword)
+	app.Put("/me/password", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.ChangePassword)
+}
@@ -3,13 +3,21 @@ package routes
 import (
 	"boilerplate/app/controllers"
 	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
 	"github.com/gofiber/fiber/v2"
 )
 
 func PostRoute(app fiber.Router) {
 	app.Get("/posts", controllers.IndexPost)
 	app.Get("/posts/:id", controllers.ShowPost)
-	app.Post("/posts", middlewares.EnableJWT(), controllers.StorePost)
-	app.Put("/posts/:id", middlewares.EnableJWT(), controllers.UpdatePost)
-	app.Delete("/posts/:id", middlewares.EnableJWT(), controllers.DestroyPost)
+	app.Get("/me/posts", middlewares.Authenticate(), controllers.IndexMyPost)
+	app.Post("/posts", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsCreate), controllers.StorePost)
+	app.Put("/posts/:id", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.UpdatePost)
+	app.Post("/posts/:id/submit", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.SubmitPost)
+	app.Post("/posts/:id/publish", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsPublishOwn, models.PermissionPostsPublishAny), controllers.PublishPost)
+	app.Post("/posts/:id/unpublish", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.UnpublishPost)
+	app.Put("/posts/:id/schedule", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.SchedulePost)
+	app.Delete("/posts/:id/schedule", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.CancelSchedulePost)
+	app.Post("/posts/:id/archive", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsUpdateOwn, models.PermissionPostsUpdateAny), controllers.ArchivePost)
+	app.Delete("/posts/:id", middlewares.Authenticate(), middlewares.RequireVerifiedEmail(), middlewares.Require(models.PermissionPostsDeleteOwn, models.PermissionPostsDeleteAny), controllers.DestroyPost)
 }
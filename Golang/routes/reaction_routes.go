@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ReactionRoute(app fiber.Router) {
+	app.Put("/posts/:id/reactions/:type", middlewares.Authenticate(), middlewares.Require(models.PermissionReactionsCreate), controllers.StoreReaction)
+	app.Delete("/posts/:id/reactions/:type", middlewares.Authenticate(), middlewares.Require(models.PermissionReactionsCreate), controllers.DestroyReaction)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+	"boilerplate/app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RoleRoute(app fiber.Router) {
+	app.Get("/roles", middlewares.EnableJWT(), middlewares.Require(models.PermissionUsersManage), controllers.IndexRole)
+	app.Put("/admin/users/:id/roles", middlewares.EnableJWT(), middlewares.DenyImpersonation(), middlewares.Require(models.PermissionUsersManage), controllers.UpdateUserRoles)
+}
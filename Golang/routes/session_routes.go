@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+	"boilerplate/app/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SessionRoute(app fiber.Router) {
+	app.Get("/me/sessions", middlewares.EnableJWT(), controllers.IndexSession)
+	app.Delete("/me/sessions/:id", middlewares.EnableJWT(), middlewares.DenyImpersonation(), controllers.DestroySession)
+}
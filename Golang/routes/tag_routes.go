@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TagRoute(app fiber.Router) {
+	app.Get("/tags", controllers.IndexTag)
+}
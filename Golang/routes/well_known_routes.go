@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"boilerplate/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func WellKnownRoute(app fiber.Router) {
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+}